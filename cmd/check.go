@@ -0,0 +1,224 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"muxic/musicutils"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// albumDisc identifies a single disc of an album
+type albumDisc struct {
+	Artist string
+	Album  string
+	Disc   int
+}
+
+// albumTracks collects the track numbers seen for one disc of an album
+type albumTracks struct {
+	Tracks      []int
+	TotalTracks int
+	Untracked   int
+}
+
+// albumEdition identifies an album regardless of edition: the album artist and the album name
+// with its edition notes stripped, both lower cased
+type albumEdition struct {
+	Artist string
+	Album  string
+}
+
+var checkEditions bool
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Reports albums that look incomplete",
+	Long: `Scans a folder of music files, groups them into albums using their album artist and album
+tags, and reports any album whose track numbers have gaps or duplicates. Nothing is modified.
+
+With --editions it instead reports albums found in more than one folder once edition notes like
+"(Deluxe Edition)" or "(Remastered)" are stripped from the album name, listing each folder and
+how many tracks it holds.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sourceFolder := strings.Trim(cmd.Flag("source").Value.String(), " ")
+
+		allFiles := musicutils.GetAllMusicFiles(context.Background(), sourceFolder, musicutils.ScanOptions{})
+
+		if checkEditions {
+			reportEditions(allFiles)
+			return
+		}
+
+		albums := make(map[albumDisc]*albumTracks)
+		for _, file := range allFiles {
+			info, err := musicutils.ReadTrackInfo(file)
+			if err != nil {
+				slog.Warn("Error reading tags", "file", file, "error", err)
+				continue
+			}
+
+			artist, album := info.AlbumKey()
+			key := albumDisc{Artist: artist, Album: album, Disc: info.DiscNumber}
+			tracks, found := albums[key]
+			if !found {
+				tracks = &albumTracks{}
+				albums[key] = tracks
+			}
+
+			if info.TrackNumber <= 0 {
+				tracks.Untracked++
+				continue
+			}
+			tracks.Tracks = append(tracks.Tracks, info.TrackNumber)
+			if info.TotalTracks > tracks.TotalTracks {
+				tracks.TotalTracks = info.TotalTracks
+			}
+		}
+
+		// Report in a stable order
+		keys := make([]albumDisc, 0, len(albums))
+		for key := range albums {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].Artist != keys[j].Artist {
+				return keys[i].Artist < keys[j].Artist
+			}
+			if keys[i].Album != keys[j].Album {
+				return keys[i].Album < keys[j].Album
+			}
+			return keys[i].Disc < keys[j].Disc
+		})
+
+		incomplete := 0
+		for _, key := range keys {
+			tracks := albums[key]
+			missing, duplicates := findTrackGaps(tracks.Tracks, tracks.TotalTracks)
+			if len(missing) == 0 && len(duplicates) == 0 {
+				continue
+			}
+			incomplete++
+
+			name := fmt.Sprintf("%s - %s", key.Artist, key.Album)
+			if key.Disc > 0 {
+				name = fmt.Sprintf("%s (disc %d)", name, key.Disc)
+			}
+
+			fmt.Println(name)
+			if len(missing) > 0 {
+				fmt.Println("  Missing tracks: ", joinInts(missing))
+			}
+			if len(duplicates) > 0 {
+				fmt.Println("  Duplicate tracks: ", joinInts(duplicates))
+			}
+			if tracks.Untracked > 0 {
+				fmt.Printf("  %d files have no track number\n", tracks.Untracked)
+			}
+		}
+
+		fmt.Printf("%d of %d albums look incomplete.\n", incomplete, len(albums))
+	},
+}
+
+// reportEditions groups the files by album regardless of edition and prints the groups that are
+// spread over more than one folder, with the number of tracks in each folder
+func reportEditions(files []string) {
+	// Album -> folder -> track count
+	albums := make(map[albumEdition]map[string]int)
+	names := make(map[albumEdition]string)
+	for _, file := range files {
+		info, err := musicutils.ReadTrackInfo(file)
+		if err != nil {
+			slog.Warn("Error reading tags", "file", file, "error", err)
+			continue
+		}
+
+		artist, album := info.AlbumKey()
+		album = musicutils.StripEdition(album)
+		key := albumEdition{Artist: strings.ToLower(artist), Album: strings.ToLower(album)}
+		if albums[key] == nil {
+			albums[key] = make(map[string]int)
+			names[key] = fmt.Sprintf("%s - %s", artist, album)
+		}
+		albums[key][filepath.Dir(file)]++
+	}
+
+	// Report in a stable order
+	keys := make([]albumEdition, 0, len(albums))
+	for key, folders := range albums {
+		if len(folders) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Artist != keys[j].Artist {
+			return keys[i].Artist < keys[j].Artist
+		}
+		return keys[i].Album < keys[j].Album
+	})
+
+	for _, key := range keys {
+		fmt.Println(names[key])
+
+		folders := make([]string, 0, len(albums[key]))
+		for folder := range albums[key] {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+		for _, folder := range folders {
+			fmt.Printf("  %s: %d tracks\n", folder, albums[key][folder])
+		}
+	}
+
+	fmt.Printf("%d of %d albums are in more than one folder.\n", len(keys), len(albums))
+}
+
+// findTrackGaps returns the track numbers missing from 1 up to the total (or the highest track
+// number seen when the total is unknown), and any track numbers that appear more than once
+func findTrackGaps(tracks []int, totalTracks int) ([]int, []int) {
+	highest := totalTracks
+	seen := make(map[int]int)
+	for _, track := range tracks {
+		seen[track]++
+		if track > highest {
+			highest = track
+		}
+	}
+
+	var missing []int
+	var duplicates []int
+	for track := 1; track <= highest; track++ {
+		switch {
+		case seen[track] == 0:
+			missing = append(missing, track)
+		case seen[track] > 1:
+			duplicates = append(duplicates, track)
+		}
+	}
+	return missing, duplicates
+}
+
+// joinInts formats a list of numbers as a comma separated string
+func joinInts(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = fmt.Sprintf("%d", n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().String("source", "", "The folder to check")
+	checkCmd.Flags().BoolVar(&checkEditions, "editions", false, "Report albums found in more than one folder, such as deluxe and standard editions, instead")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindTrackGaps(t *testing.T) {
+	tests := []struct {
+		tracks     []int
+		total      int
+		missing    []int
+		duplicates []int
+	}{
+		{[]int{1, 2, 3}, 3, nil, nil},
+		{[]int{1, 3}, 0, []int{2}, nil},
+		{[]int{1, 2, 2}, 4, []int{3, 4}, []int{2}},
+		{[]int{2}, 0, []int{1}, nil},
+	}
+	for _, test := range tests {
+		missing, duplicates := findTrackGaps(test.tracks, test.total)
+		if !slices.Equal(missing, test.missing) || !slices.Equal(duplicates, test.duplicates) {
+			t.Errorf("findTrackGaps(%v, %d) = %v, %v, expected %v, %v", test.tracks, test.total, missing, duplicates, test.missing, test.duplicates)
+		}
+	}
+}
+
+func TestCheckReportsIncompleteAlbums(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "a1.mp3"), testutil.Track("Artist", "Gappy", "One", "1/3"))
+	testutil.WriteMP3(t, filepath.Join(source, "a3.mp3"), testutil.Track("Artist", "Gappy", "Three", "3/3"))
+	testutil.WriteMP3(t, filepath.Join(source, "b1.mp3"), testutil.Track("Artist", "Whole", "One", "1/2"))
+	testutil.WriteMP3(t, filepath.Join(source, "b2.mp3"), testutil.Track("Artist", "Whole", "Two", "2/2"))
+
+	out := testutil.CaptureStdout(t, func() {
+		if err := runCommand(t, "check", "--source", source); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if !strings.Contains(out, "Artist - Gappy\n  Missing tracks:  2\n") {
+		t.Errorf("expected track 2 reported missing from Gappy, got:\n%s", out)
+	}
+	if strings.Contains(out, "Whole") {
+		t.Errorf("expected the complete album left out, got:\n%s", out)
+	}
+	if !strings.Contains(out, "1 of 2 albums look incomplete.") {
+		t.Errorf("expected the summary line, got:\n%s", out)
+	}
+}
+
+func TestCheckEditionsGroupsFolders(t *testing.T) {
+	source := t.TempDir()
+	standard := filepath.Join(source, "Album")
+	deluxe := filepath.Join(source, "Album (Deluxe Edition)")
+	testutil.WriteMP3(t, filepath.Join(standard, "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(deluxe, "01.mp3"), testutil.Track("Artist", "Album (Deluxe Edition)", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(deluxe, "02.mp3"), testutil.Track("Artist", "Album (Deluxe Edition)", "Bonus", "2"))
+	testutil.WriteMP3(t, filepath.Join(source, "Other", "01.mp3"), testutil.Track("Artist", "Other", "One", "1"))
+
+	out := testutil.CaptureStdout(t, func() {
+		if err := runCommand(t, "check", "--source", source, "--editions"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	want := "Artist - Album\n  " + standard + ": 1 tracks\n  " + deluxe + ": 2 tracks\n1 of 2 albums are in more than one folder.\n"
+	if !strings.HasSuffix(out, want) {
+		t.Errorf("expected the editions report\n%s\ngot:\n%s", want, out)
+	}
+}
@@ -4,18 +4,71 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"muxic/musicutils"
+	"muxic/organize"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
-	"github.com/punkscience/movemusic"
 	"github.com/spf13/cobra"
 )
 
 var destructive bool
+var dryRun bool
+var sidecars []string
+var strictTags []string
+var convert string
+var bitrate string
+var codec string
+var formatTargets []string
+var minTracks int
+var failFast bool
+var quarantineFolder string
+var yearFrom int
+var yearTo int
+var includeUnknownYear bool
+var includeNonMusic bool
+var excludes []string
+var trash bool
+var skippedReport string
+var unknownReport string
+var manifest string
+var bucketByLetter bool
+var keepStructure bool
+var yes bool
+var allowCopyFallback bool
+var update bool
+var includeHidden bool
+var includeEmpty bool
+var tree bool
+var sums string
+var ignoreSpace bool
+var preHook string
+var postHook string
+var since string
+var rememberDecisions bool
+var recompute bool
+var reportDuplicates bool
+var maxFiles int
+var fileMode string
+var dirMode string
+var atomicAlbum bool
+var showProgress bool
 
 // copyCmd represents the copy command
 var copyCmd = &cobra.Command{
@@ -24,62 +77,520 @@ var copyCmd = &cobra.Command{
 	Long: `Copies all music files from a specified folder into a destination file folder using their
 mp3 tag information to create the appropriate folder layout. It also cleans up the capitalization and 
 removes any special characters from the file names.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	// Catches e.g. "--strict-tags artist,title", where the list is taken as an argument
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 && cmd.Flags().Changed("strict-tags") {
+			return fmt.Errorf("unexpected argument %q; give --strict-tags its list with = (e.g. --strict-tags=artist,title)", args[0])
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get the complete list of files from the source folder
 
 		sourceFolder := strings.Trim(cmd.Flag("source").Value.String(), " ")
 		targetFolder := strings.Trim(cmd.Flag("target").Value.String(), " ")
-		destructive := cmd.Flag("destructive").Value.String() == "true"
+		if tree && !dryRun {
+			return fmt.Errorf("--tree only works with --dry-run")
+		}
+
+		// Watermarks are kept against the target as given, so dated imports share one
+		watermarkTarget := targetFolder
+
+		// Date tokens are resolved once, so a whole run lands in the same folder
+		now := time.Now()
+		if expanded := musicutils.ExpandTarget(targetFolder, now); expanded != targetFolder {
+			targetFolder = expanded
+			if !dryRun && !reportDuplicates {
+				err := os.MkdirAll(targetFolder, 0755)
+				if err != nil {
+					return fmt.Errorf("error creating target folder: %v", err)
+				}
+			}
+		}
 
-		allFiles := musicutils.GetAllMusicFiles(sourceFolder)
+		// Per-format targets, given as ext=folder
+		var targetsByFormat map[string]string
+		for _, mapping := range formatTargets {
+			ext, folder, found := strings.Cut(mapping, "=")
+			ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+			if !found || ext == "" || strings.TrimSpace(folder) == "" {
+				return fmt.Errorf("invalid --format-target %q, expected ext=folder", mapping)
+			}
+			if targetsByFormat == nil {
+				targetsByFormat = make(map[string]string)
+			}
+			targetsByFormat[ext] = musicutils.ExpandTarget(strings.TrimSpace(folder), now)
 
-		// Print all the files
-		for _, file := range allFiles {
-			if destructive {
-				fmt.Println("Moving file: ", file)
-			} else {
-				fmt.Println("Copying file: ", file)
+			// Created like a dated --target, since movemusic needs the folder to exist
+			if !dryRun && targetsByFormat[ext] != strings.TrimSpace(folder) {
+				err := os.MkdirAll(targetsByFormat[ext], 0755)
+				if err != nil {
+					return fmt.Errorf("error creating format target folder: %v", err)
+				}
 			}
+		}
 
-			resultFileName, err := movemusic.CopyMusic(file, targetFolder, true)
+		filterRegex := cmd.Flag("filter-regex").Value.String()
 
-			// Check if the file is the same as the result file
-			sameFile := resultFileName == file
+		filter := musicutils.Filter{
+			YearFrom:           yearFrom,
+			YearTo:             yearTo,
+			IncludeUnknownYear: includeUnknownYear,
+			IncludeEmpty:       includeEmpty,
+			Codec:              codec,
+		}
 
+		// Only files modified after the watermark, either the last run's or a given time
+		if since == "last" {
+			var err error
+			filter.ModifiedAfter, err = musicutils.ReadWatermark(sourceFolder, watermarkTarget)
 			if err != nil {
-				if err == movemusic.ErrFileExists {
-					fmt.Println("File already exists, skipping.")
-
-					if destructive && !sameFile {
-						// Delete the source file
-						fmt.Println("Deleting source file: ", file)
-						err := os.Remove(file)
-
-						if err != nil {
-							println("Error deleting file: ", err)
-						}
-					}
-				} else {
-					log.Println("Error copying file: ", err)
-				}
+				return fmt.Errorf("error reading the last run's watermark: %v", err)
+			}
+		} else if since != "" {
+			var err error
+			filter.ModifiedAfter, err = time.Parse(time.RFC3339, since)
+			if err != nil {
+				return fmt.Errorf("invalid --since %q, expected last or an RFC3339 time: %v", since, err)
+			}
+		}
+		started := time.Now()
 
-				continue
-			} else if destructive && !sameFile {
+		// Compile the filter up front so a bad pattern fails before scanning
+		if filterRegex != "" {
+			var err error
+			filter.Pattern, err = regexp.Compile(filterRegex)
+			if err != nil {
+				return fmt.Errorf("invalid --filter-regex %q: %v", filterRegex, err)
+			}
+		}
 
-				// Delete the source file
-				fmt.Println("Deleting source file: ", file)
-				err := os.Remove(file)
+		// On Ctrl-C stop handing out new files but let the one in flight finish
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-				if err != nil {
-					println("Error deleting file: ", err)
-				}
+		parsedFileMode, err := parseMode(fileMode)
+		if err != nil {
+			return fmt.Errorf("invalid --file-mode %q: %v", fileMode, err)
+		}
+		parsedDirMode, err := parseMode(dirMode)
+		if err != nil {
+			return fmt.Errorf("invalid --dir-mode %q: %v", dirMode, err)
+		}
+		for _, field := range strictTags {
+			if field != "artist" && field != "album" && field != "title" {
+				return fmt.Errorf("invalid --strict-tags field %q, expected artist, album or title", field)
 			}
+		}
+		switch strings.ToLower(codec) {
+		case "", "mp3", "flac", "pcm":
+		case "aac", "alac":
+			// movemusic only takes mp3, flac and wav, so .m4a files can only be copied as they are
+			if !keepStructure {
+				return fmt.Errorf("--codec %s only selects .m4a files, which can only be copied with --keep-structure", codec)
+			}
+		default:
+			return fmt.Errorf("invalid --codec %q, expected mp3, flac, pcm, aac or alac", codec)
+		}
+
+		// Prompts share one reader so piped answers aren't swallowed by the first
+		answers := bufio.NewReader(cmd.InOrStdin())
 
-			println("Finished: ", resultFileName)
+		options := organize.Options{
+			UseFolders:        true,
+			KeepStructure:     keepStructure,
+			BucketByLetter:    bucketByLetter,
+			Move:              destructive,
+			Trash:             trash,
+			Update:            update,
+			AllowCopyFallback: allowCopyFallback,
+			DryRun:            dryRun,
+			FailFast:          failFast,
+			Exclude:           excludes,
+			IncludeHidden:     includeHidden,
+			IgnoreSpace:       ignoreSpace,
+			PreHook:           preHook,
+			PostHook:          postHook,
+			Recompute:         recompute,
+			MaxFiles:          maxFiles,
+			FileMode:          parsedFileMode,
+			DirMode:           parsedDirMode,
+			AtomicAlbum:       atomicAlbum,
+			RequiredTags:      strictTags,
+			FormatTargets:     targetsByFormat,
+			MinTracks:         minTracks,
+			ConfirmTooMany: func(total int, max int) bool {
+				return confirmTooMany(answers, cmd.OutOrStdout(), total, max)
+			},
+			Filter:           filter,
+			Sidecars:         sidecars,
+			IncludeNonMusic:  includeNonMusic,
+			QuarantineFolder: quarantineFolder,
 		}
+
+		if convert != "" {
+			transcoder, err := organize.NewFFmpeg(convert, bitrate)
+			if errors.Is(err, organize.ErrNoFFmpeg) {
+				return fmt.Errorf("%v; install ffmpeg to use --convert", err)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid --convert: %v", err)
+			}
+			options.Transcoder = transcoder
+		}
+
+		// Only look for files the target already has, without touching either folder
+		if reportDuplicates {
+			return reportDuplicateFiles(ctx, organize.New(options), sourceFolder, targetFolder, filter)
+		}
+
+		if showProgress {
+			options.Progress = func(progress organize.Progress) {
+				printProgress(cmd.ErrOrStderr(), progress)
+			}
+		}
+
+		if rememberDecisions {
+			state, err := musicutils.StateDir()
+			if err != nil {
+				return fmt.Errorf("error finding the decision log: %v", err)
+			}
+			options.DecisionLog = filepath.Join(state, "decisions.json")
+		}
+
+		// The tree replaces the line per file
+		if tree {
+			options.Logger = slog.New(quietHandler{Handler: slog.Default().Handler(), level: slog.LevelWarn})
+		}
+
+		// Moving deletes the sources, so check first unless told not to
+		if destructive && !yes {
+			options.Confirm = func(total int) bool {
+				return confirmMove(answers, cmd.OutOrStdout(), total, sourceFolder, targetFolder)
+			}
+		}
+
+		summary, err := organize.New(options).Organize(ctx, sourceFolder, targetFolder)
+		if errors.Is(err, organize.ErrReadOnlySource) {
+			return fmt.Errorf("%v; use --allow-copy-fallback to copy instead", err)
+		}
+		if errors.Is(err, organize.ErrInsufficientSpace) {
+			return fmt.Errorf("%v; use --ignore-space to copy anyway", err)
+		}
+		if errors.Is(err, organize.ErrTooManyFiles) {
+			return fmt.Errorf("%v; check the source folder or raise --max-files", err)
+		}
+		if errors.Is(err, organize.ErrAborted) {
+			fmt.Println("Aborted, nothing was moved.")
+			return nil
+		}
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted, stopping.")
+		} else if err != nil {
+			return err
+		}
+		if tree {
+			printTree(os.Stdout, targetFolder, buildTree(targetFolder, summary.Results))
+		}
+		printSummary(summary)
+
+		unknown := unknownResults(summary)
+		if len(unknown) > 0 {
+			fmt.Printf("%d files were filed under Unknown, check their tags:\n", len(unknown))
+			for _, result := range unknown {
+				fmt.Printf("  %s -> %s\n", result.Source, result.Destination)
+			}
+		}
+
+		if manifest != "" {
+			err := writeManifest(manifest, summary)
+			if err != nil {
+				return fmt.Errorf("error writing manifest: %v", err)
+			}
+		}
+
+		if sums != "" {
+			err := writeSums(sums, targetFolder, summary)
+			if err != nil {
+				return fmt.Errorf("error writing sums: %v", err)
+			}
+		}
+
+		if skippedReport != "" {
+			err := writeSkippedReport(skippedReport, summary)
+			if err != nil {
+				return fmt.Errorf("error writing skipped report: %v", err)
+			}
+		}
+
+		if unknownReport != "" {
+			err := writeUnknownReport(unknownReport, unknown)
+			if err != nil {
+				return fmt.Errorf("error writing unknown report: %v", err)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(summary.Errors) > 0 {
+			return fmt.Errorf("%d files failed", len(summary.Errors))
+		}
+
+		// The next --since last run picks up from when this one started
+		if since != "" && !dryRun {
+			err := musicutils.WriteWatermark(sourceFolder, watermarkTarget, started)
+			if err != nil {
+				return fmt.Errorf("error saving the watermark: %v", err)
+			}
+		}
+		return nil
 	},
 }
 
+// printProgress writes a line showing how far through its bytes the run is and the time left
+func printProgress(out io.Writer, progress organize.Progress) {
+	line := fmt.Sprintf("%.0f%% (%.1f of %.1f MB, %d of %d files)", progress.Percent,
+		float64(progress.Bytes)/(1024*1024), float64(progress.TotalBytes)/(1024*1024), progress.Files, progress.TotalFiles)
+	if progress.ETA > 0 {
+		line += fmt.Sprintf(", about %s left", progress.ETA.Round(time.Second))
+	}
+	fmt.Fprintln(out, line)
+}
+
+// confirmMove asks whether to go ahead with moving the files and returns true only if the
+// answer is "yes"
+func confirmMove(in *bufio.Reader, out io.Writer, total int, sourceFolder string, targetFolder string) bool {
+	fmt.Fprintf(out, "About to move %d files from %s to %s, deleting the originals.\n", total, sourceFolder, targetFolder)
+	fmt.Fprint(out, "Type yes to continue: ")
+
+	answer, _ := in.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(answer)) == "yes"
+}
+
+// parseMode parses an octal permission string such as 0644; an empty string gives 0, meaning
+// the default permissions are kept
+func parseMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if value > 0777 {
+		return 0, fmt.Errorf("permissions must be between 0 and 0777")
+	}
+	return os.FileMode(value), nil
+}
+
+// confirmTooMany asks whether to carry on with more files than the --max-files cap and returns
+// true only if the answer is "yes"
+func confirmTooMany(in *bufio.Reader, out io.Writer, total int, max int) bool {
+	fmt.Fprintf(out, "Found %d music files, more than the --max-files limit of %d.\n", total, max)
+	fmt.Fprint(out, "Type yes to process them all anyway: ")
+
+	answer, _ := in.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(answer)) == "yes"
+}
+
+// printSummary prints how many files were processed, lists the ones that failed and breaks down
+// why files were skipped
+func printSummary(summary organize.Summary) {
+	fmt.Printf("Run %s completed %d of %d files (%d errors).\n", runID, summary.Completed, summary.Total, len(summary.Errors))
+	if summary.BytesRemoved > 0 {
+		// Trashed files still take up room until the trash is emptied
+		removed := "freed"
+		if trash {
+			removed = "sent to the trash"
+		}
+		fmt.Printf("Wrote %s to the target, %s %s from the source.\n", formatBytes(summary.BytesWritten), removed, formatBytes(summary.BytesRemoved))
+	} else if summary.BytesWritten > 0 {
+		fmt.Printf("Wrote %s to the target.\n", formatBytes(summary.BytesWritten))
+	}
+	for _, fe := range summary.Errors {
+		fmt.Printf("  %s: %v\n", fe.Path, fe.Err)
+	}
+
+	if len(summary.Skipped) > 0 {
+		counts := make(map[string]int)
+		for _, skipped := range summary.Skipped {
+			counts[skipped.Reason]++
+		}
+		reasons := make([]string, 0, len(counts))
+		for reason := range counts {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+
+		fmt.Printf("Skipped %d files:\n", len(summary.Skipped))
+		for _, reason := range reasons {
+			fmt.Printf("  %s: %d\n", reason, counts[reason])
+		}
+	}
+}
+
+// formatBytes formats a byte count in the largest unit that keeps it above one, e.g. 1.5 GB
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, suffix := float64(bytes), 0
+	for value >= unit && suffix < 4 {
+		value /= unit
+		suffix++
+	}
+	return fmt.Sprintf("%.1f %s", value, []string{"B", "KB", "MB", "GB", "TB"}[suffix])
+}
+
+// writeManifest writes a CSV file with a row for every file processed
+func writeManifest(manifestFile string, summary organize.Summary) error {
+	out, err := os.Create(manifestFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	w.Write([]string{"run", "source", "destination", "operation", "bytes", "status"})
+	for _, result := range summary.Results {
+		w.Write([]string{
+			runID,
+			result.Source,
+			result.Destination,
+			manifestOperation(result.Status),
+			strconv.FormatInt(result.Bytes, 10),
+			string(result.Status),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// manifestOperation returns what was done to a file: a copy that fell back from a move is a
+// copy, a dry run is whatever the run would have done, and skipped and failed files had nothing
+// done to them
+func manifestOperation(status organize.Status) string {
+	switch status {
+	case organize.StatusCopied:
+		return "copy"
+	case organize.StatusMoved:
+		return "move"
+	case organize.StatusDryRun:
+		if destructive {
+			return "move"
+		}
+		return "copy"
+	default:
+		return "none"
+	}
+}
+
+// writeSums writes a sha256sum style file, checkable with sha256sum -c from the target folder,
+// with the hash of every file placed in the target. The hashes are read back from the target,
+// so they describe what actually landed on disk. A dry run places nothing, so it lists nothing.
+func writeSums(sumsFile string, targetFolder string, summary organize.Summary) error {
+	out, err := os.Create(sumsFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, result := range summary.Results {
+		if result.Destination == "" || result.Status == organize.StatusFailed || result.Status == organize.StatusDryRun {
+			continue
+		}
+
+		hash, err := musicutils.FileSHA256(result.Destination)
+		if err != nil {
+			return err
+		}
+
+		path, inside := musicutils.RelPathInside(targetFolder, result.Destination)
+		if !inside {
+			path = result.Destination
+		}
+		_, err = fmt.Fprintf(out, "%s  %s\n", hash, filepath.ToSlash(path))
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Close()
+}
+
+// writeSkippedReport writes every skipped or failed file with its reason, one per line, as
+// reason<TAB>path
+func writeSkippedReport(reportFile string, summary organize.Summary) error {
+	out, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, skipped := range summary.Skipped {
+		_, err = fmt.Fprintf(out, "%s\t%s\n", skipped.Reason, skipped.Path)
+		if err != nil {
+			return err
+		}
+	}
+	for _, fe := range summary.Errors {
+		_, err = fmt.Fprintf(out, "error\t%s\n", fe.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Close()
+}
+
+// unknownResults returns the files placed in this run that had no artist or album tag, and so
+// were filed under Unknown
+func unknownResults(summary organize.Summary) []organize.Result {
+	var unknown []organize.Result
+	for _, result := range summary.Results {
+		if result.Status != organize.StatusCopied && result.Status != organize.StatusMoved {
+			continue
+		}
+
+		// A moved file's source is gone, but its copy carries the same tags
+		file := result.Source
+		if !musicutils.FileExists(file) {
+			file = result.Destination
+		}
+		info, _ := musicutils.ReadTrackInfo(file)
+		if slices.Contains(info.Missing, "artist") || slices.Contains(info.Missing, "album") {
+			unknown = append(unknown, result)
+		}
+	}
+	return unknown
+}
+
+// writeUnknownReport writes the source and destination of each file filed under Unknown, one
+// tab separated pair per line
+func writeUnknownReport(reportFile string, unknown []organize.Result) error {
+	out, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, result := range unknown {
+		_, err = fmt.Fprintf(out, "%s\t%s\n", result.Source, result.Destination)
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Close()
+}
+
 func init() {
 	rootCmd.AddCommand(copyCmd)
 
@@ -91,7 +602,49 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	copyCmd.Flags().String("source", "", "The source folder name")
-	copyCmd.Flags().String("target", "", "The destination folder name")
+	copyCmd.Flags().String("source", "", "The source folder name, or a single music file")
+	copyCmd.Flags().String("target", "", "The destination folder name, which may contain date tokens like {date:2006-01-02}")
 	copyCmd.Flags().BoolVarP(&destructive, "move", "m", false, "Delete the source file after copying")
+	copyCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Move without asking for confirmation first")
+	copyCmd.Flags().BoolVar(&allowCopyFallback, "allow-copy-fallback", false, "In move mode, copy without removing the sources if the source folder is read-only")
+	copyCmd.Flags().BoolVar(&trash, "trash", false, "In move mode, send source files to the trash instead of deleting them")
+	copyCmd.Flags().BoolVar(&keepStructure, "keep-structure", false, "Mirror each file's path under the source folder instead of building folders from its tags")
+	copyCmd.Flags().BoolVar(&bucketByLetter, "bucket-by-letter", false, "File each artist folder under a folder for its first letter (A-Z, or # for anything else)")
+	copyCmd.Flags().BoolVar(&update, "update", false, "Replace files already in the target when the source file is newer")
+	copyCmd.Flags().StringVar(&fileMode, "file-mode", "", "Octal permissions for files placed in the target, e.g. 0644 (default: leave as created)")
+	copyCmd.Flags().StringVar(&dirMode, "dir-mode", "", "Octal permissions for folders the copy creates in the target, e.g. 0755 (default: leave as created)")
+	copyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without copying anything")
+	copyCmd.Flags().BoolVar(&reportDuplicates, "report-duplicates", false, "Only list the source files already in the target with the same contents, without copying anything")
+	copyCmd.Flags().BoolVar(&tree, "tree", false, "With --dry-run, show the resulting folder tree with file counts instead of a line per file")
+	copyCmd.Flags().String("filter-regex", "", "Only process files whose full path matches this regular expression")
+	copyCmd.Flags().StringArrayVar(&excludes, "exclude", nil, "Glob pattern for files or folders to leave out (can be repeated), on top of the source's .muxicignore")
+	copyCmd.Flags().BoolVar(&includeHidden, "include-hidden", false, "Also process files and folders whose names start with a dot")
+	copyCmd.Flags().StringVar(&since, "since", "", "Only process files modified after this RFC3339 time, or after the last --since run with \"last\"")
+	copyCmd.Flags().IntVar(&yearFrom, "year-from", 0, "Only process files tagged with this year or later")
+	copyCmd.Flags().IntVar(&yearTo, "year-to", 0, "Only process files tagged with this year or earlier")
+	copyCmd.Flags().BoolVar(&includeUnknownYear, "include-unknown-year", false, "Keep files with no year tag when --year-from or --year-to is set")
+	copyCmd.Flags().BoolVar(&includeEmpty, "include-empty", false, "Also process zero-byte music files, which are otherwise skipped")
+	copyCmd.Flags().BoolVar(&ignoreSpace, "ignore-space", false, "Don't check that the target has enough free space before starting")
+	copyCmd.Flags().IntVar(&maxFiles, "max-files", 100000, "Stop and ask before processing more than this many files (0 for no limit)")
+	copyCmd.Flags().BoolVar(&showProgress, "progress", false, "Show the percentage of bytes copied and an estimate of the time left after each file")
+	copyCmd.Flags().IntVar(&minTracks, "min-tracks", 0, "Only copy albums with at least this many tracks in the source, skipping stray singles (0 for all)")
+	copyCmd.Flags().BoolVar(&atomicAlbum, "atomic-album", false, "Place each source folder's tracks all or nothing, undoing an album's copies if one of its tracks fails")
+	copyCmd.Flags().BoolVar(&failFast, "fail-fast", false, "Stop at the first file that fails instead of carrying on")
+	copyCmd.Flags().StringVar(&quarantineFolder, "quarantine", "", "Folder to copy (or move) files that fail processing into, with the reasons in quarantine.log")
+	copyCmd.Flags().BoolVar(&includeNonMusic, "include-non-music", false, "Also carry cover art, booklets and other non-music files into each album folder")
+	copyCmd.Flags().BoolVar(&rememberDecisions, "remember", false, "Remember each file's destination in decisions.json under ~/.muxic (or $MUXIC_HOME) and reuse it on later runs")
+	copyCmd.Flags().BoolVar(&recompute, "recompute", false, "With --remember, work every destination out afresh instead of reusing the remembered one")
+	copyCmd.Flags().StringVar(&preHook, "pre-hook", "", "Command to run before each file is processed, with the source path as its last argument. Split on spaces without a shell, so the program path and its arguments can't contain spaces; use a script for anything more")
+	copyCmd.Flags().StringVar(&postHook, "post-hook", "", "Command to run after each file is copied or moved, with the source and destination paths as its last arguments. Split on spaces like --pre-hook")
+	copyCmd.Flags().StringVar(&manifest, "manifest", "", "Write a CSV of every file processed, with its destination, operation, size and status, to this file")
+	copyCmd.Flags().StringVar(&sums, "sums", "", "Write the SHA-256 of every file placed in the target to this file, in sha256sum -c format")
+	copyCmd.Flags().StringVar(&unknownReport, "unknown-report", "", "Write the source and destination of each file filed under Unknown to this file")
+	copyCmd.Flags().StringVar(&skippedReport, "skipped-report", "", "Write each skipped or failed file and the reason to this file")
+	copyCmd.Flags().StringArrayVar(&formatTargets, "format-target", nil, "Send files with an extension to their own folder, as ext=folder, e.g. flac=/music/lossless (can be repeated); others go to --target")
+	copyCmd.Flags().StringVar(&codec, "codec", "", "Only copy files in this codec: mp3, flac or pcm (wav), or with --keep-structure aac or alac for .m4a files")
+	copyCmd.Flags().StringVar(&convert, "convert", "", "Convert each file to this format (mp3, aac or opus) with ffmpeg instead of copying it as is")
+	copyCmd.Flags().StringVar(&bitrate, "bitrate", "", "Bitrate for --convert, e.g. 192k; the default is ffmpeg's")
+	copyCmd.Flags().StringSliceVar(&strictTags, "strict-tags", nil, "Fail files missing any of these tags (artist, album, title) instead of filing them under Unknown; on its own requires all three, otherwise give the list with = (e.g. --strict-tags=artist,title)")
+	copyCmd.Flags().Lookup("strict-tags").NoOptDefVal = "artist,album,title"
+	copyCmd.Flags().StringSliceVar(&sidecars, "sidecars", nil, "Extensions of sidecar files (e.g. cue,log,lrc) to carry along with each track")
 }
@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"muxic/internal/testutil"
+	"muxic/musicutils"
+	"muxic/organize"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyFailsWhenFilesFail(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), nil)
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	err := runCommand(t, "copy", "--source", source, "--target", missing)
+	if err == nil {
+		t.Error("expected a failed file to fail the command")
+	}
+}
+
+func TestCopySkipsDoNotFail(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "untagged.mp3"), nil)
+
+	for run := 1; run <= 2; run++ {
+		err := runCommand(t, "copy", "--source", source, "--target", target)
+		if err != nil {
+			t.Errorf("run %d: expected success, got %v", run, err)
+		}
+	}
+}
+
+func TestTargetInsideSourceIsNotScanned(t *testing.T) {
+	source := t.TempDir()
+	target := filepath.Join(source, "organized")
+	testutil.WriteMP3(t, filepath.Join(source, "new.mp3"), testutil.Track("Artist", "Album", "New", "1"))
+	old := filepath.Join(target, "Artist", "Album", "02 - Old.mp3")
+	testutil.WriteMP3(t, old, testutil.Track("Other", "Album", "Old", "2"))
+
+	err := runCommand(t, "copy", "--source", source, "--target", target, "--move", "--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Had the target been scanned, the old file would have moved under Other
+	for _, file := range []string{filepath.Join(target, "Artist", "Album", "01 - New.mp3"), old} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("expected %s: %v", file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "Other")); err == nil {
+		t.Error("expected the file already in the target to be left alone")
+	}
+}
+
+func TestSkippedReport(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "new.mp3"), map[string]string{"TIT2": "New", "TYER": "2001"})
+	testutil.WriteMP3(t, filepath.Join(source, "old.mp3"), map[string]string{"TIT2": "Old", "TYER": "1975"})
+	report := filepath.Join(t.TempDir(), "skipped.txt")
+
+	err := runCommand(t, "copy", "--source", source, "--target", target, "--year-from", "2000", "--skipped-report", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := musicutils.SkipFilteredByYear + "\t" + filepath.Join(source, "old.mp3") + "\n"; string(data) != want {
+		t.Errorf("expected the report %q, got %q", want, data)
+	}
+}
+
+func TestManifestRecordsEachFile(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	file := filepath.Join(source, "song.mp3")
+	testutil.WriteMP3(t, file, testutil.Track("Artist", "Album", "Song", "1"))
+	stat, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(t.TempDir(), "manifest.csv")
+
+	err = runCommand(t, "copy", "--source", source, "--target", target, "--move", "--dry-run", "--manifest", manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"run", "source", "destination", "operation", "bytes", "status"},
+		{runID, file, filepath.Join(target, "Artist", "Album", "01 - Song.mp3"), "move", strconv.FormatInt(stat.Size(), 10), "dry-run"},
+	}
+	if rows := readCSV(t, manifest); !slices.EqualFunc(rows, want, slices.Equal) {
+		t.Errorf("expected %v, got %v", want, rows)
+	}
+}
+
+func TestManifestOperationFollowsStatus(t *testing.T) {
+	defer func(saved bool) { destructive = saved }(destructive)
+	destructive = true
+
+	summary := organize.Summary{Results: []organize.Result{
+		{Source: "moved.mp3", Destination: "out/moved.mp3", Status: organize.StatusMoved},
+		{Source: "fallback.mp3", Destination: "out/fallback.mp3", Status: organize.StatusCopied},
+		{Source: "there.mp3", Destination: "out/there.mp3", Status: organize.StatusSkipped},
+		{Source: "broken.mp3", Status: organize.StatusFailed},
+		{Source: "planned.mp3", Status: organize.StatusDryRun},
+	}}
+
+	manifest := filepath.Join(t.TempDir(), "manifest.csv")
+	err := writeManifest(manifest, summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var operations []string
+	for _, row := range readCSV(t, manifest)[1:] {
+		operations = append(operations, row[3])
+	}
+	want := []string{"move", "copy", "none", "none", "move"}
+	if !slices.Equal(operations, want) {
+		t.Errorf("expected operations %v, got %v", want, operations)
+	}
+
+	destructive = false
+	if op := manifestOperation(organize.StatusDryRun); op != "copy" {
+		t.Errorf("expected a dry run copy to be a copy, got %s", op)
+	}
+}
+
+// readCSV reads all the rows of a CSV file
+func readCSV(t *testing.T, file string) [][]string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestMoveAsksFirst(t *testing.T) {
+	for _, answer := range []string{"no", "yes"} {
+		source, target := t.TempDir(), t.TempDir()
+		song := filepath.Join(source, "song.mp3")
+		testutil.WriteMP3(t, song, nil)
+
+		err := runCommandWithInput(t, answer+"\n", "copy", "--move", "--source", source, "--target", target)
+		if err != nil {
+			t.Fatalf("%s: unexpected failure: %v", answer, err)
+		}
+
+		moved := !musicutils.FileExists(song)
+		if moved != (answer == "yes") {
+			t.Errorf("answering %s: expected moved to be %v", answer, answer == "yes")
+		}
+	}
+}
+
+func TestCopySingleFile(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	song, other := filepath.Join(source, "song.mp3"), filepath.Join(source, "other.mp3")
+	testutil.WriteMP3(t, song, testutil.Track("Artist", "Album", "Song", "1"))
+	testutil.WriteMP3(t, other, testutil.Track("Artist", "Album", "Other", "2"))
+
+	err := runCommand(t, "copy", "--move", "--yes", "--source", song, "--target", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, []string{"Artist/Album/01 - Song.mp3"}) {
+		t.Errorf("expected just the given file at its tagged destination, found %v", files)
+	}
+	if musicutils.FileExists(song) || !musicutils.FileExists(other) {
+		t.Error("expected only the given file to be moved")
+	}
+}
+
+func TestDatedTargetIsCreated(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	err := runCommand(t, "copy", "--source", source, "--target", filepath.Join(target, "{date:2006-01-02}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dated := filepath.Join(target, time.Now().Format("2006-01-02"))
+	if !musicutils.FileExists(filepath.Join(dated, "Artist", "Album", "01 - Song.mp3")) {
+		t.Errorf("expected the file under %s", dated)
+	}
+}
+
+func TestCopyIncludeHidden(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, ".incoming", "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	for _, includeHidden := range []bool{false, true} {
+		args := []string{"copy", "--source", source, "--target", target}
+		if includeHidden {
+			args = append(args, "--include-hidden")
+		}
+		err := runCommand(t, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		copied := musicutils.FileExists(filepath.Join(target, "Artist", "Album", "01 - Song.mp3"))
+		if copied != includeHidden {
+			t.Errorf("include hidden %v: expected copied to be %v", includeHidden, includeHidden)
+		}
+	}
+}
+
+func TestSumsMatchSha256sum(t *testing.T) {
+	target, sums := t.TempDir(), filepath.Join(t.TempDir(), "sha256sums")
+	placed := filepath.Join(target, "Artist", "Album", "01 - Song.mp3")
+	testutil.WriteFile(t, placed, []byte("abc"))
+
+	summary := organize.Summary{Results: []organize.Result{
+		{Source: "song.mp3", Destination: placed, Status: organize.StatusCopied},
+		{Source: "planned.mp3", Destination: filepath.Join(target, "Artist", "Album", "02 - Planned.mp3"), Status: organize.StatusDryRun},
+		{Source: "broken.mp3", Status: organize.StatusFailed},
+	}}
+	err := writeSums(sums, target, summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad  Artist/Album/01 - Song.mp3\n"
+	if string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]os.FileMode{"": 0, "0644": 0644, "755": 0755, "0": 0}
+	for mode, want := range tests {
+		got, err := parseMode(mode)
+		if err != nil || got != want {
+			t.Errorf("parseMode(%q) = %v, %v, expected %v", mode, got, err, want)
+		}
+	}
+	for _, mode := range []string{"0800", "rw-r--r--", "01000"} {
+		if _, err := parseMode(mode); err == nil {
+			t.Errorf("expected parseMode(%q) to fail", mode)
+		}
+	}
+}
+
+func TestRememberUsesMuxicHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(musicutils.HomeEnv, home)
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	err := runCommand(t, "copy", "--remember", "--source", source, "--target", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !musicutils.FileExists(filepath.Join(home, "decisions.json")) {
+		t.Errorf("expected the decision log in %s", home)
+	}
+}
+
+func TestStrictTagsListNeedsEquals(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	err := runCommand(t, "copy", "--source", source, "--target", target, "--strict-tags", "artist,title")
+	if err == nil || !strings.Contains(err.Error(), "--strict-tags=artist,title") {
+		t.Errorf("expected the stray list to be rejected with a hint, got %v", err)
+	}
+
+	err = runCommand(t, "copy", "--source", source, "--target", target, "stray")
+	if err == nil || strings.Contains(err.Error(), "--strict-tags") {
+		t.Errorf("expected a stray argument without --strict-tags to be rejected without the hint, got %v", err)
+	}
+
+	err = runCommand(t, "copy", "--source", source, "--target", target, "--strict-tags=artist,title")
+	if err != nil {
+		t.Errorf("expected the list given with = to work, got %v", err)
+	}
+	if !slices.Equal(strictTags, []string{"artist", "title"}) {
+		t.Errorf("expected artist and title required, got %v", strictTags)
+	}
+}
+
+func TestUnknownReportListsUntaggedFiles(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	report := filepath.Join(t.TempDir(), "unknown.txt")
+	testutil.WriteFile(t, filepath.Join(source, "untagged.mp3"), []byte{0xff, 0xfb, 0x90, 0x00})
+	testutil.WriteMP3(t, filepath.Join(source, "no-album.mp3"), map[string]string{"TPE1": "Artist", "TIT2": "Single"})
+	testutil.WriteMP3(t, filepath.Join(source, "tagged.mp3"), map[string]string{"TPE1": "Artist", "TALB": "Album", "TIT2": "Song"})
+
+	var err error
+	output := testutil.CaptureStdout(t, func() {
+		err = runCommand(t, "copy", "--source", source, "--target", target, "--unknown-report", report)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	slices.Sort(lines)
+	want := []string{
+		filepath.Join(source, "no-album.mp3") + "\t" + filepath.Join(target, "Artist", "Unknown", "00 - Single.mp3"),
+		filepath.Join(source, "untagged.mp3") + "\t" + filepath.Join(target, "Unknown", "Unknown", "01 - Untagged.mp3"),
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected the report\n%v\ngot\n%v", want, lines)
+	}
+	if !strings.Contains(output, "2 files were filed under Unknown") {
+		t.Errorf("expected the unknown files in the output, got\n%s", output)
+	}
+}
+
+func TestM4aCodecNeedsKeepStructure(t *testing.T) {
+	for _, codec := range []string{"aac", "alac"} {
+		err := runCommand(t, "copy", "--source", t.TempDir(), "--target", t.TempDir(), "--codec", codec)
+		if err == nil || !strings.Contains(err.Error(), "--keep-structure") {
+			t.Errorf("%s: expected to be told to use --keep-structure, got %v", codec, err)
+		}
+
+		err = runCommand(t, "copy", "--source", t.TempDir(), "--target", t.TempDir(), "--codec", codec, "--keep-structure")
+		if err != nil {
+			t.Errorf("%s: expected --keep-structure to allow it, got %v", codec, err)
+		}
+	}
+
+	err := runCommand(t, "copy", "--source", t.TempDir(), "--target", t.TempDir(), "--codec", "vorbis")
+	if err == nil {
+		t.Error("expected an unknown codec to be rejected")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:          "0 B",
+		1023:       "1023 B",
+		1536:       "1.5 KB",
+		40 << 30:   "40.0 GB",
+		3 << 40:    "3.0 TB",
+		5000 << 40: "5000.0 TB",
+	}
+	for bytes, want := range tests {
+		if got := formatBytes(bytes); got != want {
+			t.Errorf("formatBytes(%d) = %q, expected %q", bytes, got, want)
+		}
+	}
+}
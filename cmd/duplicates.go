@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"muxic/musicutils"
+	"muxic/organize"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// reportDuplicateFiles lists every source file whose destination in the target already holds the
+// same contents, the way verify compares them. Nothing is copied, so it can be used to clean an
+// incoming folder before importing it.
+func reportDuplicateFiles(ctx context.Context, organizer *organize.Organizer, source string, targetFolder string, filter musicutils.Filter) error {
+	// Destinations are worked out relative to the source folder, even for a single file
+	sourceFolder := source
+	if stat, err := os.Stat(source); err == nil && !stat.IsDir() {
+		sourceFolder = filepath.Dir(source)
+	}
+
+	scan, err := organizer.ScanOptions(source, targetFolder)
+	if err != nil {
+		return err
+	}
+	files, _ := musicutils.GetFilteredMusicFiles(ctx, source, scan, filter)
+
+	results := verifyFiles(ctx, organizer, files, sourceFolder, targetFolder, runtime.NumCPU())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	duplicates := 0
+	for _, result := range results {
+		if result.Err == nil && result.Problem == "" {
+			duplicates++
+			fmt.Printf("duplicate: %s -> %s\n", result.Source, result.Destination)
+		}
+	}
+
+	fmt.Printf("Found %d duplicates among %d files.\n", duplicates, len(results))
+	return nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReportDuplicates(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	same := filepath.Join(source, "same.mp3")
+	testutil.WriteMP3(t, same, testutil.Track("Artist", "Album", "Same", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "changed.mp3"), testutil.Track("Artist", "Album", "Changed", "2"))
+	testutil.WriteMP3(t, filepath.Join(source, "new.mp3"), testutil.Track("Artist", "Album", "New", "3"))
+
+	// The same file is already in the library, and an older edit of the changed one
+	data, err := os.ReadFile(same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFile(t, filepath.Join(target, "Artist", "Album", "01 - Same.mp3"), data)
+	testutil.WriteFile(t, filepath.Join(target, "Artist", "Album", "02 - Changed.mp3"), []byte("older"))
+
+	out := testutil.CaptureStdout(t, func() {
+		err := runCommand(t, "copy", "--source", source, "--target", target, "--report-duplicates")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	duplicate := "duplicate: " + same + " -> " + filepath.Join(target, "Artist", "Album", "01 - Same.mp3")
+	if !strings.Contains(out, duplicate) || strings.Count(out, "duplicate: ") != 1 {
+		t.Errorf("expected only %q to be reported, got\n%s", duplicate, out)
+	}
+	if !strings.Contains(out, "Found 1 duplicates among 3 files.") {
+		t.Errorf("expected the count of duplicates, got\n%s", out)
+	}
+
+	want := []string{"Artist/Album/01 - Same.mp3", "Artist/Album/02 - Changed.mp3"}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+		t.Errorf("expected nothing to be copied, found %v", files)
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runCommand runs muxic with the arguments, starting from and leaving every flag at its
+// default
+func runCommand(t *testing.T, args ...string) error {
+	t.Helper()
+	return runCommandWithInput(t, "", args...)
+}
+
+// runCommandWithInput runs muxic like runCommand, with the input as what's typed at prompts
+func runCommandWithInput(t *testing.T, input string, args ...string) error {
+	t.Helper()
+	t.Cleanup(func() {
+		resetFlags(rootCmd)
+		rootCmd.SetArgs(nil)
+		rootCmd.SetIn(nil)
+	})
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(strings.NewReader(input))
+	return rootCmd.Execute()
+}
+
+// resetFlags puts the flags of the command and its subcommands back to their defaults
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
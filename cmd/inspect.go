@@ -0,0 +1,162 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"muxic/musicutils"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <archive.zip>",
+	Short: "Lists the music files inside a zip archive and their tags",
+	Long: `Reads a zip archive and lists each music file inside it, in whatever folder, with its artist,
+album, track and title tags. Other entries are ignored. Nothing is extracted.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archive, err := zip.OpenReader(args[0])
+		if err != nil {
+			return fmt.Errorf("error opening archive: %v", err)
+		}
+		defer archive.Close()
+
+		// One reader for the whole archive, so its buffers are reused from entry to entry
+		var reader zipEntryReader
+		found := 0
+		for _, entry := range archive.File {
+			if entry.FileInfo().IsDir() || !musicutils.IsMusicFile(entry.Name) || musicutils.IsHidden(path.Base(entry.Name)) {
+				continue
+			}
+			found++
+
+			info, err := reader.readTrackInfo(entry)
+			if err != nil {
+				slog.Warn("Error reading tags", "file", entry.Name, "error", err)
+			}
+			fmt.Printf("%s\n  %s - %s - %02d - %s\n", entry.Name, info.Artist, info.Album, info.TrackNumber, info.Title)
+		}
+
+		fmt.Printf("%d music files in %s\n", found, args[0])
+		return nil
+	},
+}
+
+// zipEntryHead and zipEntryTail are how much of each end of an archive entry is kept in memory
+// for reading its tags. Tags and stream headers sit at the start, and ID3v1 tags and some MP4
+// indexes at the end; the middle is only audio.
+const (
+	zipEntryHead = 16 << 20
+	zipEntryTail = 1 << 20
+)
+
+// zipEntryReader reads the ends of archive entries. Its buffers are reused from one entry to
+// the next, so an entry it returns is only good until the next read.
+type zipEntryReader struct {
+	head []byte
+	tail []byte
+}
+
+// readTrackInfo reads the tags of a zip entry. Entries can't be seeked, so the ends of the
+// entry are read into memory first.
+func (z *zipEntryReader) readTrackInfo(entry *zip.File) (musicutils.TrackInfo, error) {
+	partial, err := z.read(entry)
+	if err != nil {
+		// Still give the defaults, as ReadTrackInfo does for a file it can't open
+		info, _ := musicutils.ReadTrackInfoFrom(bytes.NewReader(nil), entry.Name)
+		return info, err
+	}
+	return musicutils.ReadTrackInfoFrom(io.NewSectionReader(partial, 0, partial.size), entry.Name)
+}
+
+// partialEntry holds the two ends of an archive entry, reading as zeros in between
+type partialEntry struct {
+	head []byte
+	tail []byte
+	size int64
+}
+
+// read reads the head and tail of a zip entry. The head is no bigger than the entry says it
+// is, and however large it turns out to be, only zipEntryHead and twice zipEntryTail bytes are
+// held at once.
+func (z *zipEntryReader) read(entry *zip.File) (*partialEntry, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	headSize := int(min(zipEntryHead, entry.UncompressedSize64))
+	if cap(z.head) < headSize {
+		z.head = make([]byte, headSize)
+	}
+	partial := &partialEntry{head: z.head[:headSize]}
+	n, err := io.ReadFull(rc, partial.head)
+	partial.head = partial.head[:n]
+	partial.size = int64(n)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return partial, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Stream the rest, keeping only the last zipEntryTail bytes
+	if z.tail == nil {
+		z.tail = make([]byte, 2*zipEntryTail)
+	}
+	filled := 0
+	for {
+		if filled == len(z.tail) {
+			copy(z.tail, z.tail[zipEntryTail:])
+			filled = zipEntryTail
+		}
+		n, err := rc.Read(z.tail[filled:])
+		filled += n
+		partial.size += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	partial.tail = z.tail[max(0, filled-zipEntryTail):filled]
+	return partial, nil
+}
+
+// ReadAt reads from the head or tail of the entry, with zeros for the part not kept
+func (p *partialEntry) ReadAt(b []byte, off int64) (int, error) {
+	tailStart := p.size - int64(len(p.tail))
+	n := 0
+	for n < len(b) && off+int64(n) < p.size {
+		pos := off + int64(n)
+		switch {
+		case pos < int64(len(p.head)):
+			n += copy(b[n:], p.head[pos:])
+		case pos >= tailStart:
+			n += copy(b[n:], p.tail[pos-tailStart:])
+		default:
+			gap := int(min(int64(len(b)-n), tailStart-pos))
+			clear(b[n : n+gap])
+			n += gap
+		}
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+}
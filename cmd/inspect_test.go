@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"archive/zip"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip writes a zip archive with the given entries and returns the open reader
+func writeZip(t *testing.T, entries map[string][]byte) *zip.ReadCloser {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(out)
+	for name, data := range entries {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(data)
+	}
+	w.Close()
+	out.Close()
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { archive.Close() })
+	return archive
+}
+
+// zipEntry returns the named entry of the archive
+func zipEntry(t *testing.T, archive *zip.ReadCloser, name string) *zip.File {
+	t.Helper()
+	for _, entry := range archive.File {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	t.Fatalf("entry %s not found", name)
+	return nil
+}
+
+func TestReadZipTrackInfo(t *testing.T) {
+	archive := writeZip(t, map[string][]byte{
+		"Album/CD1/01.mp3": testutil.MP3(map[string]string{"TPE1": "Artist", "TIT2": "Song"}, 400),
+		"Album/notes.txt":  []byte("not music"),
+	})
+
+	var reader zipEntryReader
+	info, err := reader.readTrackInfo(zipEntry(t, archive, "Album/CD1/01.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Artist != "Artist" || info.Title != "Song" {
+		t.Errorf("expected Artist - Song, got %q - %q", info.Artist, info.Title)
+	}
+}
+
+func TestReadZipEntryKeepsOnlyTheEnds(t *testing.T) {
+	data := testutil.MP3(map[string]string{"TPE1": "Artist", "TIT2": "Long Song"}, zipEntryHead+3*zipEntryTail)
+	copy(data[len(data)-3:], "end")
+	archive := writeZip(t, map[string][]byte{"long.mp3": data})
+
+	var reader zipEntryReader
+	partial, err := reader.read(archive.File[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.size != int64(len(data)) {
+		t.Errorf("expected a size of %d, got %d", len(data), partial.size)
+	}
+	if len(partial.head) != zipEntryHead || len(partial.tail) != zipEntryTail {
+		t.Errorf("expected only the ends to be kept, got %d and %d bytes", len(partial.head), len(partial.tail))
+	}
+
+	end := make([]byte, 3)
+	partial.ReadAt(end, partial.size-3)
+	if string(end) != "end" {
+		t.Errorf("expected the tail to read back, got %q", end)
+	}
+
+	info, err := reader.readTrackInfo(archive.File[0])
+	if err != nil || info.Title != "Long Song" {
+		t.Errorf("expected the tags to be read, got %q, %v", info.Title, err)
+	}
+}
+
+func TestZipEntryReaderSizesAndReusesTheHead(t *testing.T) {
+	small := testutil.MP3(map[string]string{"TIT2": "Small"}, 400)
+	archive := writeZip(t, map[string][]byte{
+		"small.mp3":  small,
+		"medium.mp3": testutil.MP3(map[string]string{"TIT2": "Medium"}, 4000),
+	})
+
+	var reader zipEntryReader
+	_, err := reader.read(zipEntry(t, archive, "medium.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	medium := cap(reader.head)
+	if medium > 5000 {
+		t.Errorf("expected the head sized to the entry, got %d bytes", medium)
+	}
+
+	partial, err := reader.read(zipEntry(t, archive, "small.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partial.head) != len(small) || cap(reader.head) != medium || &partial.head[0] != &reader.head[0] {
+		t.Errorf("expected the small entry read into the same buffer, got %d bytes in a buffer of %d", len(partial.head), cap(reader.head))
+	}
+}
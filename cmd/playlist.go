@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// playlistCmd represents the playlist command
+var playlistCmd = &cobra.Command{
+	Use:   "playlist",
+	Short: "Writes an M3U8 playlist of all music files in a folder",
+	Long: `Scans a folder of music files and writes an extended M3U8 playlist with the length, artist
+and title of every track. With --by-album a playlist is written into each album folder instead.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFolder := strings.Trim(cmd.Flag("source").Value.String(), " ")
+		outFile := strings.Trim(cmd.Flag("out").Value.String(), " ")
+		absolute := cmd.Flag("absolute").Value.String() == "true"
+		byAlbum := cmd.Flag("by-album").Value.String() == "true"
+
+		allFiles := musicutils.GetAllMusicFiles(context.Background(), sourceFolder, musicutils.ScanOptions{})
+
+		if !byAlbum {
+			return writePlaylistFile(outFile, allFiles, absolute)
+		}
+
+		// One playlist per album folder, named after the folder
+		albums := make(map[string][]string)
+		for _, file := range allFiles {
+			dir := filepath.Dir(file)
+			albums[dir] = append(albums[dir], file)
+		}
+
+		for dir, files := range albums {
+			err := writePlaylistFile(filepath.Join(dir, filepath.Base(dir)+".m3u8"), files, absolute)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// writePlaylistFile writes a playlist of the files, with paths relative to the playlist's own
+// folder unless absolute paths are wanted
+func writePlaylistFile(outFile string, files []string, absolute bool) error {
+	baseDir, err := filepath.Abs(filepath.Dir(outFile))
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(files)
+
+	var entries []musicutils.PlaylistEntry
+	for _, file := range files {
+		info, err := musicutils.ReadTrackInfo(file)
+		if err != nil {
+			slog.Warn("Error reading tags", "file", file, "error", err)
+		}
+
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		if !absolute {
+			rel, err := filepath.Rel(baseDir, path)
+			if err == nil {
+				path = rel
+			}
+		}
+
+		seconds := -1
+		if info.Duration > 0 {
+			seconds = int(info.Duration.Seconds())
+		}
+
+		entries = append(entries, musicutils.PlaylistEntry{
+			Path:    path,
+			Seconds: seconds,
+			Artist:  info.Artist,
+			Title:   info.Title,
+		})
+	}
+
+	out, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	err = musicutils.WritePlaylist(out, entries)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d tracks to %s\n", len(entries), outFile)
+	return out.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(playlistCmd)
+
+	playlistCmd.Flags().String("source", "", "The folder to build the playlist from")
+	playlistCmd.Flags().String("out", "library.m3u8", "The playlist file to write")
+	playlistCmd.Flags().Bool("absolute", false, "Write absolute paths instead of paths relative to the playlist")
+	playlistCmd.Flags().Bool("by-album", false, "Write one playlist into each album folder instead of a single playlist")
+}
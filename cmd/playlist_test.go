@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlaylistPaths(t *testing.T) {
+	library := t.TempDir()
+	testutil.WriteFile(t, filepath.Join(library, "Artist", "Album", "01 - One.flac"), testutil.FLAC(map[string]string{"ARTIST": "Artist", "TITLE": "One"}, nil))
+	testutil.WriteMP3(t, filepath.Join(library, "Artist", "Album", "02 - Two.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+
+	tests := []struct {
+		args []string
+		out  string
+		want string
+	}{
+		{
+			nil,
+			filepath.Join(library, "library.m3u8"),
+			"#EXTM3U\n#EXTINF:10,Artist - One\n" + filepath.Join("Artist", "Album", "01 - One.flac") + "\n#EXTINF:-1,Artist - Two\n" + filepath.Join("Artist", "Album", "02 - Two.mp3") + "\n",
+		},
+		{
+			[]string{"--absolute"},
+			filepath.Join(t.TempDir(), "library.m3u8"),
+			"#EXTM3U\n#EXTINF:10,Artist - One\n" + filepath.Join(library, "Artist", "Album", "01 - One.flac") + "\n#EXTINF:-1,Artist - Two\n" + filepath.Join(library, "Artist", "Album", "02 - Two.mp3") + "\n",
+		},
+		{
+			[]string{"--by-album"},
+			filepath.Join(library, "Artist", "Album", "Album.m3u8"),
+			"#EXTM3U\n#EXTINF:10,Artist - One\n01 - One.flac\n#EXTINF:-1,Artist - Two\n02 - Two.mp3\n",
+		},
+	}
+	for _, test := range tests {
+		args := append([]string{"playlist", "--source", library, "--out", test.out}, test.args...)
+		if err := runCommand(t, args...); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(test.out)
+		if err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if string(data) != test.want {
+			t.Errorf("%v: got:\n%s\nwant:\n%s", test.args, data, test.want)
+		}
+		os.Remove(test.out)
+	}
+}
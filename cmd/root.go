@@ -5,12 +5,24 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+	"log/slog"
+	"muxic/musicutils"
 	"os"
+	"runtime"
+	"runtime/pprof"
 
 	"github.com/spf13/cobra"
 )
 
+// runID identifies this invocation in the logs and reports
+var runID = musicutils.NewRunID()
 
+var verbose bool
+var logFormat string
+var cpuProfile string
+var memProfile string
+var cpuProfileFile *os.File
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -25,23 +37,85 @@ to quickly create a Cobra application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+
+	// Set up the logger every command uses before it runs
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		logger, err := musicutils.NewLogger(os.Stderr, verbose, logFormat)
+		if err != nil {
+			return err
+		}
+		slog.SetDefault(logger.With("run", runID))
+
+		return startProfiling()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	stopProfiling()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// startProfiling starts the CPU profile, if one was asked for
+func startProfiling() error {
+	if cpuProfile == "" {
+		return nil
+	}
+
+	f, err := os.Create(cpuProfile)
+	if err != nil {
+		return fmt.Errorf("error creating CPU profile: %v", err)
+	}
+	err = pprof.StartCPUProfile(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error starting CPU profile: %v", err)
+	}
+	cpuProfileFile = f
+	return nil
+}
+
+// stopProfiling flushes the CPU profile and writes the heap profile, if they were asked for
+func stopProfiling() {
+	if cpuProfileFile != nil {
+		pprof.StopCPUProfile()
+		cpuProfileFile.Close()
+		cpuProfileFile = nil
+	}
+
+	if memProfile != "" {
+		f, err := os.Create(memProfile)
+		if err != nil {
+			slog.Error("Error creating memory profile", "error", err)
+			return
+		}
+		defer f.Close()
+
+		// Get up to date statistics
+		runtime.GC()
+		err = pprof.WriteHeapProfile(f)
+		if err != nil {
+			slog.Error("Error writing memory profile", "error", err)
+		}
+	}
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.muxic.yaml)")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log debug messages too")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format, text or json")
+	rootCmd.PersistentFlags().StringVar(&cpuProfile, "cpuprofile", "", "Write a CPU profile to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfile, "memprofile", "", "Write a heap profile to this file")
+	rootCmd.PersistentFlags().MarkHidden("cpuprofile")
+	rootCmd.PersistentFlags().MarkHidden("memprofile")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log/slog"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnknownLogFormatFails(t *testing.T) {
+	err := runCommand(t, "check", "--source", t.TempDir(), "--log-format", "xml")
+	if err == nil {
+		t.Error("expected an unknown log format to fail the command")
+	}
+}
+
+func TestProfilesAreWritten(t *testing.T) {
+	folder := t.TempDir()
+	cpu, mem := filepath.Join(folder, "cpu.prof"), filepath.Join(folder, "mem.prof")
+
+	err := runCommand(t, "copy", "--dry-run", "--source", t.TempDir(), "--target", t.TempDir(), "--cpuprofile", cpu, "--memprofile", mem)
+	stopProfiling()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, profile := range []string{cpu, mem} {
+		stat, err := os.Stat(profile)
+		if err != nil || stat.Size() == 0 {
+			t.Errorf("expected %s to be written, got %v", filepath.Base(profile), err)
+		}
+	}
+}
+
+func TestLogLinesCarryTheRunID(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	// The logger writes to stderr, so catch it in a file for the run
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	saved, savedLogger := os.Stderr, slog.Default()
+	os.Stderr = stderr
+	defer func() {
+		os.Stderr = saved
+		slog.SetDefault(savedLogger)
+	}()
+
+	err = runCommand(t, "copy", "--log-format", "json", "--source", source, "--target", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected several log lines, got %q", data)
+	}
+	for _, line := range lines {
+		var record struct{ Run string }
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil || record.Run != runID {
+			t.Errorf("expected run %s in %s", runID, line)
+		}
+	}
+}
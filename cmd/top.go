@@ -0,0 +1,115 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"muxic/musicutils"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// libraryShare totals up the tracks and bytes belonging to one artist or album
+type libraryShare struct {
+	Name   string
+	Tracks int
+	Bytes  int64
+}
+
+// topCmd represents the top command
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Ranks the artists and albums taking up the most of a library",
+	Long: `Scans a folder of music files and lists the artists with the most tracks (or bytes) and the
+largest albums, to help decide what to prune. Nothing is modified.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFolder := strings.Trim(cmd.Flag("source").Value.String(), " ")
+		top, _ := cmd.Flags().GetInt("top")
+		by := cmd.Flag("by").Value.String()
+
+		if by != "count" && by != "size" {
+			return fmt.Errorf("unknown --by %q, expected count or size", by)
+		}
+
+		allFiles := musicutils.GetAllMusicFiles(context.Background(), sourceFolder, musicutils.ScanOptions{})
+
+		artists := make(map[string]*libraryShare)
+		albums := make(map[string]*libraryShare)
+		for _, file := range allFiles {
+			info, err := musicutils.ReadTrackInfo(file)
+			if err != nil {
+				slog.Warn("Error reading tags", "file", file, "error", err)
+			}
+
+			var size int64
+			if stat, err := os.Stat(file); err == nil {
+				size = stat.Size()
+			}
+
+			artist, album := info.AlbumKey()
+			addShare(artists, artist, size)
+			addShare(albums, fmt.Sprintf("%s - %s", artist, album), size)
+		}
+
+		fmt.Printf("Top artists by %s:\n", by)
+		printShares(rankShares(artists, by), top)
+		fmt.Printf("Top albums by %s:\n", by)
+		printShares(rankShares(albums, by), top)
+		return nil
+	},
+}
+
+// addShare adds a track of the given size to the named share
+func addShare(shares map[string]*libraryShare, name string, size int64) {
+	share, found := shares[name]
+	if !found {
+		share = &libraryShare{Name: name}
+		shares[name] = share
+	}
+	share.Tracks++
+	share.Bytes += size
+}
+
+// rankShares sorts the shares largest first by track count or size, breaking ties by name
+func rankShares(shares map[string]*libraryShare, by string) []*libraryShare {
+	ranked := make([]*libraryShare, 0, len(shares))
+	for _, share := range shares {
+		ranked = append(ranked, share)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if by == "size" && a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if by == "count" && a.Tracks != b.Tracks {
+			return a.Tracks > b.Tracks
+		}
+		return a.Name < b.Name
+	})
+	return ranked
+}
+
+// printShares prints the first few shares with their track counts and sizes
+func printShares(ranked []*libraryShare, top int) {
+	if top > 0 && len(ranked) > top {
+		ranked = ranked[:top]
+	}
+	for i, share := range ranked {
+		fmt.Printf("  %3d. %s: %d tracks, %.1f MB\n", i+1, share.Name, share.Tracks, float64(share.Bytes)/(1024*1024))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+
+	topCmd.Flags().String("source", "", "The folder to rank")
+	topCmd.Flags().Int("top", 10, "How many artists and albums to list (0 for all)")
+	topCmd.Flags().String("by", "count", "Rank by track count or total size: count or size")
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTopRanksTheLeaderFirst(t *testing.T) {
+	source := t.TempDir()
+	files := map[string][]byte{
+		"a1.mp3":  testutil.MP3(map[string]string{"TPE1": "Leader", "TIT2": "One"}, 100),
+		"a2.mp3":  testutil.MP3(map[string]string{"TPE1": "Leader", "TIT2": "Two"}, 100),
+		"a3.mp3":  testutil.MP3(map[string]string{"TPE1": "Leader", "TIT2": "Three"}, 100),
+		"big.mp3": testutil.MP3(map[string]string{"TPE1": "Heavy", "TIT2": "Long"}, 100000),
+	}
+	for name, data := range files {
+		testutil.WriteFile(t, filepath.Join(source, name), data)
+	}
+
+	tests := map[string]string{"count": "1. Leader: 3 tracks", "size": "1. Heavy: 1 tracks"}
+	for by, want := range tests {
+		var err error
+		output := testutil.CaptureStdout(t, func() {
+			err = runCommand(t, "top", "--source", source, "--by", by, "--top", "1")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		artists := strings.SplitN(output, "Top albums", 2)[0]
+		if !strings.Contains(artists, want) || strings.Count(artists, "tracks") != 1 {
+			t.Errorf("by %s: expected only %q, got\n%s", by, want, output)
+		}
+	}
+}
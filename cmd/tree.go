@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"muxic/musicutils"
+	"muxic/organize"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeNode is a folder in the planned destination tree, with the number of files that would
+// end up anywhere below it
+type treeNode struct {
+	files    int
+	children map[string]*treeNode
+}
+
+// buildTree puts the planned destination of every result under the target folder into a tree
+// of folders. Failed files, which have no destination, are left out.
+func buildTree(targetFolder string, results []organize.Result) *treeNode {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for _, result := range results {
+		if result.Destination == "" || result.Status == organize.StatusFailed {
+			continue
+		}
+		rel, inside := musicutils.RelPathInside(targetFolder, result.Destination)
+		if !inside {
+			continue
+		}
+
+		node := root
+		node.files++
+		for _, folder := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+			if folder == "." {
+				continue
+			}
+			child, found := node.children[folder]
+			if !found {
+				child = &treeNode{children: make(map[string]*treeNode)}
+				node.children[folder] = child
+			}
+			child.files++
+			node = child
+		}
+	}
+	return root
+}
+
+// printTree writes the tree as ASCII art, one folder per line with its file count, folders in
+// name order
+func printTree(w io.Writer, name string, node *treeNode) {
+	fmt.Fprintf(w, "%s (%s)\n", name, fileCount(node.files))
+	node.print(w, "")
+}
+
+// print writes the node's subfolders, each line starting with the prefix
+func (n *treeNode) print(w io.Writer, prefix string) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := n.children[name]
+		branch, indent := "|-- ", "|   "
+		if i == len(names)-1 {
+			branch, indent = "`-- ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, branch, name, fileCount(child.files))
+		child.print(w, prefix+indent)
+	}
+}
+
+// fileCount formats a number of files, e.g. "1 file" or "12 files"
+func fileCount(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// quietHandler passes on only the records at or above its level, so a report can replace the
+// per-file log lines without hiding warnings and errors
+type quietHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h quietHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h quietHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return quietHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h quietHandler) WithGroup(name string) slog.Handler {
+	return quietHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
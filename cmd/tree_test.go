@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"muxic/organize"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintTree(t *testing.T) {
+	target := "library"
+	results := []organize.Result{
+		{Destination: filepath.Join(target, "Artist", "Album", "01 - One.mp3"), Status: organize.StatusDryRun},
+		{Destination: filepath.Join(target, "Artist", "Album", "02 - Two.mp3"), Status: organize.StatusDryRun},
+		{Destination: filepath.Join(target, "Artist", "Live", "01 - One.mp3"), Status: organize.StatusDryRun},
+		{Destination: filepath.Join(target, "Other", "Album", "01 - Song.mp3"), Status: organize.StatusDryRun},
+		{Source: "broken.mp3", Status: organize.StatusFailed},
+	}
+
+	var out strings.Builder
+	printTree(&out, target, buildTree(target, results))
+
+	expected := strings.Join([]string{
+		"library (4 files)",
+		"|-- Artist (3 files)",
+		"|   |-- Album (2 files)",
+		"|   `-- Live (1 file)",
+		"`-- Other (1 file)",
+		"    `-- Album (1 file)",
+		"",
+	}, "\n")
+	if out.String() != expected {
+		t.Errorf("expected tree\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestCopyDryRunTree(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "one.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "two.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Other", "Single", "Song", "1"))
+
+	out := testutil.CaptureStdout(t, func() {
+		err := runCommand(t, "copy", "--source", source, "--target", target, "--dry-run", "--tree")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, line := range []string{
+		target + " (3 files)",
+		"|-- Artist (2 files)",
+		"|   `-- Album (2 files)",
+		"`-- Other (1 file)",
+		"    `-- Single (1 file)",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("expected the output to contain %q, got\n%s", line, out)
+		}
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected nothing to be copied, got %v", files)
+	}
+}
+
+func TestTreeNeedsDryRun(t *testing.T) {
+	err := runCommand(t, "copy", "--source", t.TempDir(), "--target", t.TempDir(), "--tree")
+	if err == nil {
+		t.Error("expected --tree without --dry-run to fail")
+	}
+}
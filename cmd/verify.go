@@ -0,0 +1,178 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"muxic/musicutils"
+	"muxic/organize"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// Problems verify can find with a file
+const (
+	verifyMissing         = "missing"
+	verifySizeMismatch    = "size mismatch"
+	verifyContentMismatch = "content mismatch"
+)
+
+// verifyResult is what verify found for one source file. Problem is empty when the copy matches.
+type verifyResult struct {
+	Source      string
+	Destination string
+	Problem     string
+	Err         error
+}
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Checks that every source file made it to the target intact",
+	Long: `Works out where copy puts each music file in the source folder, then checks that a file
+exists there with the same size and SHA-256 hash. Missing files, size mismatches and content
+mismatches are listed, and the command fails if there are any. Use the same --keep-structure and
+--bucket-by-letter flags the copy used. Nothing is modified.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFolder := strings.Trim(cmd.Flag("source").Value.String(), " ")
+		targetFolder := strings.Trim(cmd.Flag("target").Value.String(), " ")
+		workers, err := cmd.Flags().GetInt("workers")
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		organizer := organize.New(organize.Options{
+			UseFolders:     true,
+			KeepStructure:  cmd.Flag("keep-structure").Value.String() == "true",
+			BucketByLetter: cmd.Flag("bucket-by-letter").Value.String() == "true",
+		})
+		scan, err := organizer.ScanOptions(sourceFolder, targetFolder)
+		if err != nil {
+			return err
+		}
+		files := musicutils.GetAllMusicFiles(ctx, sourceFolder, scan)
+
+		results := verifyFiles(ctx, organizer, files, sourceFolder, targetFolder, workers)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		problems := 0
+		for _, result := range results {
+			switch {
+			case result.Err != nil:
+				problems++
+				fmt.Printf("error: %s: %v\n", result.Source, result.Err)
+			case result.Problem != "":
+				problems++
+				fmt.Printf("%s: %s -> %s\n", result.Problem, result.Source, result.Destination)
+			}
+		}
+
+		fmt.Printf("Verified %d files, %d problems.\n", len(results), problems)
+		if problems > 0 {
+			return fmt.Errorf("%d of %d files didn't verify", problems, len(results))
+		}
+		return nil
+	},
+}
+
+// verifyFiles checks each file against its destination using a pool of workers, and returns the
+// results in the same order as the files
+func verifyFiles(ctx context.Context, organizer *organize.Organizer, files []string, sourceFolder string, targetFolder string, workers int) []verifyResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]verifyResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job] = verifyFile(organizer, files[job], sourceFolder, targetFolder)
+			}
+		}()
+	}
+
+	for i := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// verifyFile checks that the file's destination exists with the same size and contents
+func verifyFile(organizer *organize.Organizer, file string, sourceFolder string, targetFolder string) verifyResult {
+	result := verifyResult{Source: file}
+
+	destination, err := organizer.Destination(file, sourceFolder, targetFolder)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Destination = destination
+
+	sourceStat, err := os.Stat(file)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	targetStat, err := os.Stat(destination)
+	if os.IsNotExist(err) {
+		result.Problem = verifyMissing
+		return result
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if sourceStat.Size() != targetStat.Size() {
+		result.Problem = verifySizeMismatch
+		return result
+	}
+
+	sourceHash, err := musicutils.FileSHA256(file)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	targetHash, err := musicutils.FileSHA256(destination)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if sourceHash != targetHash {
+		result.Problem = verifyContentMismatch
+	}
+	return result
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().String("source", "", "The source folder that was copied")
+	verifyCmd.Flags().String("target", "", "The target folder it was copied to")
+	verifyCmd.Flags().Bool("keep-structure", false, "The copy mirrored the source paths instead of building folders from the tags")
+	verifyCmd.Flags().Bool("bucket-by-letter", false, "The copy filed artist folders under first-letter folders")
+	verifyCmd.Flags().Int("workers", runtime.NumCPU(), "Number of files to hash at the same time")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyReportsDamagedCopies(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	for _, title := range []string{"Fine", "Gone", "Longer", "Changed"} {
+		testutil.WriteMP3(t, filepath.Join(source, title+".mp3"), testutil.Track("Artist", "Album", title, "1"))
+	}
+	err := runCommand(t, "copy", "--source", source, "--target", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := testutil.CaptureStdout(t, func() {
+		if err := runCommand(t, "verify", "--source", source, "--target", target, "--workers", "2"); err != nil {
+			t.Errorf("expected an intact copy to verify, got %v", err)
+		}
+	})
+	if !strings.Contains(out, "Verified 4 files, 0 problems.") {
+		t.Errorf("expected a clean summary, got:\n%s", out)
+	}
+
+	album := filepath.Join(target, "Artist", "Album")
+	err = os.Remove(filepath.Join(album, "01 - Gone.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	longer, err := os.ReadFile(filepath.Join(album, "01 - Longer.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFile(t, filepath.Join(album, "01 - Longer.mp3"), append(longer, 0))
+	changed, err := os.ReadFile(filepath.Join(album, "01 - Changed.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed[len(changed)-1] ^= 0xff
+	testutil.WriteFile(t, filepath.Join(album, "01 - Changed.mp3"), changed)
+
+	out = testutil.CaptureStdout(t, func() {
+		if err := runCommand(t, "verify", "--source", source, "--target", target); err == nil {
+			t.Error("expected the damaged copy to fail verification")
+		}
+	})
+	for _, want := range []string{
+		"missing: " + filepath.Join(source, "Gone.mp3"),
+		"size mismatch: " + filepath.Join(source, "Longer.mp3"),
+		"content mismatch: " + filepath.Join(source, "Changed.mp3"),
+		"Verified 4 files, 3 problems.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the report, got:\n%s", want, out)
+		}
+	}
+}
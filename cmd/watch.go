@@ -0,0 +1,239 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"muxic/musicutils"
+	"muxic/organize"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+// pendingFile tracks a file that has been written to but hasn't settled yet
+type pendingFile struct {
+	lastEvent time.Time
+	size      int64
+}
+
+// settler debounces file events: a file is ready once it has had no events for the settle
+// period and its size has stopped changing between two checks
+type settler struct {
+	settle  time.Duration
+	pending map[string]*pendingFile
+}
+
+// newSettler returns a settler that waits the given period after a file's last event
+func newSettler(settle time.Duration) *settler {
+	return &settler{settle: settle, pending: make(map[string]*pendingFile)}
+}
+
+// touch records an event for the file, pushing its deadline out again
+func (s *settler) touch(file string, now time.Time) {
+	if p, found := s.pending[file]; found {
+		p.lastEvent = now
+	} else {
+		s.pending[file] = &pendingFile{lastEvent: now, size: -1}
+	}
+}
+
+// forget stops tracking a file that was removed or renamed
+func (s *settler) forget(file string) {
+	delete(s.pending, file)
+}
+
+// ready returns the files that have settled, sorted by name, and stops tracking them. A file
+// that is still growing is given another settle period, and one that has gone is dropped.
+func (s *settler) ready(now time.Time) []string {
+	var files []string
+	for file, p := range s.pending {
+		if now.Sub(p.lastEvent) < s.settle {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			delete(s.pending, file)
+			continue
+		}
+
+		// Still growing, so it's still being written
+		if info.Size() != p.size {
+			p.size = info.Size()
+			p.lastEvent = now
+			continue
+		}
+
+		delete(s.pending, file)
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watches a folder and organizes new music files as they arrive",
+	Long: `Watches a source folder (an inbox) for new music files and copies or moves each one into the
+destination folder once it has finished being written, using the same logic as the copy command.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFolder := strings.Trim(cmd.Flag("source").Value.String(), " ")
+		targetFolder := strings.Trim(cmd.Flag("target").Value.String(), " ")
+		destructive := cmd.Flag("move").Value.String() == "true"
+		dryRun := cmd.Flag("dry-run").Value.String() == "true"
+		settle, _ := cmd.Flags().GetDuration("settle")
+		excludes, _ := cmd.Flags().GetStringArray("exclude")
+		includeHidden, _ := cmd.Flags().GetBool("include-hidden")
+		preHook := cmd.Flag("pre-hook").Value.String()
+		postHook := cmd.Flag("post-hook").Value.String()
+
+		if settle <= 0 {
+			return fmt.Errorf("the settle time must be greater than zero")
+		}
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("error creating watcher: %v", err)
+		}
+		defer watcher.Close()
+
+		organizer := organize.New(organize.Options{
+			UseFolders:    true,
+			Move:          destructive,
+			DryRun:        dryRun,
+			Exclude:       excludes,
+			IncludeHidden: includeHidden,
+			PreHook:       preHook,
+			PostHook:      postHook,
+		})
+
+		// Scan the inbox the way copy scans its source: a target inside it isn't watched, or every
+		// organized file would come back in, hidden files are left out unless --include-hidden is
+		// given, and the .muxicignore and --exclude patterns apply
+		scan, err := organizer.ScanOptions(sourceFolder, targetFolder)
+		if err != nil {
+			return err
+		}
+		patterns, err := musicutils.ReadIgnoreFile(sourceFolder)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", musicutils.IgnoreFileName, err)
+		}
+		scan.Exclude = append(patterns, scan.Exclude...)
+
+		// fsnotify doesn't recurse, so every folder under the source gets its own watch
+		_, err = watchFolders(watcher, sourceFolder, sourceFolder, scan)
+		if err != nil {
+			return fmt.Errorf("error watching source folder: %v", err)
+		}
+
+		slog.Info("Watching for new music files", "folder", sourceFolder)
+
+		// Stop cleanly on Ctrl-C, letting a file in flight finish
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		pending := newSettler(settle)
+		ticker := time.NewTicker(settle / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("Stopped watching", "folder", sourceFolder)
+				return nil
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+
+				if event.Has(fsnotify.Create) {
+					info, err := os.Stat(event.Name)
+					if err == nil && info.IsDir() {
+						// Pick up new folders and anything already dropped into them
+						files, err := watchFolders(watcher, sourceFolder, event.Name, scan)
+						if err != nil {
+							slog.Error("Error watching folder", "folder", event.Name, "error", err)
+						}
+						for _, file := range files {
+							pending.touch(file, time.Now())
+						}
+						continue
+					}
+				}
+
+				if (event.Has(fsnotify.Create) || event.Has(fsnotify.Write)) && musicutils.IsMusicFile(event.Name) && !scan.Skips(sourceFolder, event.Name, false) {
+					pending.touch(event.Name, time.Now())
+				}
+
+				if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+					pending.forget(event.Name)
+				}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return nil
+				}
+				slog.Error("Error watching files", "error", err)
+
+			case <-ticker.C:
+				for _, file := range pending.ready(time.Now()) {
+					organizer.ProcessFile(ctx, file, targetFolder)
+				}
+			}
+		}
+	},
+}
+
+// watchFolders adds a watch for the folder and all of its subfolders, and returns the music
+// files already in them. Anything the scan options leave out of a scan of root is skipped.
+func watchFolders(watcher *fsnotify.Watcher, root string, folder string, scan musicutils.ScanOptions) ([]string, error) {
+	var files []string
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if scan.Skips(root, path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		if musicutils.IsMusicFile(info.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().String("source", "", "The source (inbox) folder name to watch")
+	watchCmd.Flags().String("target", "", "The destination folder name")
+	watchCmd.Flags().BoolP("move", "m", false, "Delete the source file after copying")
+	watchCmd.Flags().Bool("dry-run", false, "Show what would be done without copying anything")
+	watchCmd.Flags().StringArray("exclude", nil, "Glob pattern for files or folders to leave out (can be repeated), on top of the source's .muxicignore")
+	watchCmd.Flags().Bool("include-hidden", false, "Also process files and folders whose names start with a dot")
+	watchCmd.Flags().String("pre-hook", "", "Command to run before each file is processed, with the source path as its last argument. Split on spaces without a shell, so the program path and its arguments can't contain spaces; use a script for anything more")
+	watchCmd.Flags().String("post-hook", "", "Command to run after each file is copied or moved, with the source and destination paths as its last arguments. Split on spaces like --pre-hook")
+	watchCmd.Flags().Duration("settle", 2*time.Second, "How long a file must be unchanged before it is processed")
+}
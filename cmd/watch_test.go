@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"muxic/internal/testutil"
+	"muxic/musicutils"
+	"muxic/organize"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestSettlerWaitsForFilesToStopChanging(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "song.mp3")
+	if err := os.WriteFile(file, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	settle := 2 * time.Second
+	start := time.Now()
+	s := newSettler(settle)
+	s.touch(file, start)
+
+	// Too soon after the last event
+	if got := s.ready(start.Add(settle / 2)); len(got) != 0 {
+		t.Fatalf("ready before the settle period: %v", got)
+	}
+
+	// The first check only records the size
+	if got := s.ready(start.Add(settle)); len(got) != 0 {
+		t.Fatalf("ready before the size was checked twice: %v", got)
+	}
+
+	// It grew, so it's still being written and waits another period
+	if err := os.WriteFile(file, []byte("partial and more"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.ready(start.Add(2 * settle)); len(got) != 0 {
+		t.Fatalf("ready while still growing: %v", got)
+	}
+
+	// A new event pushes the deadline out again
+	s.touch(file, start.Add(2*settle))
+	if got := s.ready(start.Add(2*settle + settle/2)); len(got) != 0 {
+		t.Fatalf("ready right after a new event: %v", got)
+	}
+
+	if got := s.ready(start.Add(3 * settle)); !slices.Equal(got, []string{file}) {
+		t.Fatalf("ready = %v, want %v", got, []string{file})
+	}
+
+	// Once handed out, a file isn't returned again
+	if got := s.ready(start.Add(10 * settle)); len(got) != 0 {
+		t.Fatalf("ready again: %v", got)
+	}
+}
+
+func TestSettlerDropsRemovedFiles(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.mp3")
+	renamed := filepath.Join(dir, "renamed.mp3")
+	deleted := filepath.Join(dir, "deleted.mp3")
+	for _, file := range []string{kept, renamed} {
+		if err := os.WriteFile(file, []byte("music"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	s := newSettler(time.Second)
+	s.touch(kept, start)
+	s.touch(renamed, start)
+	s.touch(deleted, start)
+	s.forget(renamed)
+
+	s.ready(start.Add(time.Second))
+	if got := s.ready(start.Add(2 * time.Second)); !slices.Equal(got, []string{kept}) {
+		t.Fatalf("ready = %v, want %v", got, []string{kept})
+	}
+}
+
+func TestWatchFoldersUsesTheCopyScan(t *testing.T) {
+	source := t.TempDir()
+	target := filepath.Join(source, "organized")
+	testutil.WriteMP3(t, filepath.Join(source, "new", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "new", "02.wav"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "incomplete", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "sorted", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, ".partial", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "new", "._01.mp3"), nil)
+	testutil.WriteFile(t, filepath.Join(source, "sorted", musicutils.SkipMarkerName), nil)
+	testutil.WriteMP3(t, filepath.Join(target, "Artist", "Album", "01 - Old.mp3"), nil)
+
+	organizer := organize.New(organize.Options{Exclude: []string{"incomplete/", "*.wav"}})
+	scan, err := organizer.ScanOptions(source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	files, err := watchFolders(watcher, source, source, scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watched := watcher.WatchList()
+	slices.Sort(watched)
+	if want := []string{source, filepath.Join(source, "new")}; !slices.Equal(watched, want) {
+		t.Errorf("watching %v, want %v", watched, want)
+	}
+	if want := []string{filepath.Join(source, "new", "01.mp3")}; !slices.Equal(files, want) {
+		t.Errorf("found %v, want %v", files, want)
+	}
+
+	// Patterns stay relative to the inbox when a new folder is picked up
+	files, err = watchFolders(watcher, source, filepath.Join(source, "new"), musicutils.ScanOptions{Exclude: []string{"new/01.mp3", "*.wav"}})
+	if err != nil || len(files) != 0 {
+		t.Errorf("expected the new folder's file left out, found %v, %v", files, err)
+	}
+}
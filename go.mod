@@ -3,13 +3,13 @@ module muxic
 go 1.23.1
 
 require (
+	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
+	github.com/fsnotify/fsnotify v1.8.0
 	github.com/punkscience/movemusic v1.0.9
 	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/sys v0.13.0
+	golang.org/x/text v0.20.0
 )
 
-require (
-	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8 // indirect
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/text v0.20.0 // indirect
-)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
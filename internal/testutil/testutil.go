@@ -0,0 +1,221 @@
+// Package testutil holds the music file fixtures and other helpers shared by muxic's tests.
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// ID3Tag returns an ID3v2.3 tag holding the given text frames, e.g. "TPE1" for the artist.
+// Frames with an empty value are left out.
+func ID3Tag(frames map[string]string) []byte {
+	ids := make([]string, 0, len(frames))
+	for id, value := range frames {
+		if value != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var body bytes.Buffer
+	for _, id := range ids {
+		data := append([]byte{0}, frames[id]...)
+		body.WriteString(id)
+		binary.Write(&body, binary.BigEndian, uint32(len(data)))
+		body.Write([]byte{0, 0})
+		body.Write(data)
+	}
+
+	size := body.Len()
+	var tag bytes.Buffer
+	tag.WriteString("ID3")
+	tag.Write([]byte{3, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)})
+	tag.Write(body.Bytes())
+	return tag.Bytes()
+}
+
+// MP3 returns a minimal MP3 file tagged with the given text frames: the tag, one silent MPEG
+// frame header and the given number of padding bytes
+func MP3(frames map[string]string, padding int) []byte {
+	data := ID3Tag(frames)
+	data = append(data, 0xff, 0xfb, 0x90, 0x00)
+	return append(data, make([]byte, padding)...)
+}
+
+// Track returns the text frames for a tagged track
+func Track(artist string, album string, title string, number string) map[string]string {
+	return map[string]string{"TPE1": artist, "TALB": album, "TIT2": title, "TRCK": number}
+}
+
+// FLAC returns a minimal FLAC stream, 10 seconds long at 44.1kHz, with a STREAMINFO block and
+// Vorbis comments holding the given fields, e.g. "ARTIST". An ID3 tag with the id3 frames is put
+// in front when id3 isn't nil, the way some taggers do.
+func FLAC(comments map[string]string, id3 map[string]string) []byte {
+	var data []byte
+	if id3 != nil {
+		data = append(data, ID3Tag(id3)...)
+	}
+	data = append(data, "fLaC"...)
+
+	// STREAMINFO: 44.1kHz, 441000 samples
+	info := make([]byte, 34)
+	info[10], info[11], info[12] = 0x0a, 0xc4, 0x40
+	binary.BigEndian.PutUint32(info[14:18], 441000)
+	data = append(data, 0, 0, 0, 34)
+	data = append(data, info...)
+
+	keys := make([]string, 0, len(comments))
+	for key := range comments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	vorbis := binary.LittleEndian.AppendUint32(nil, 4)
+	vorbis = append(vorbis, "test"...)
+	vorbis = binary.LittleEndian.AppendUint32(vorbis, uint32(len(keys)))
+	for _, key := range keys {
+		field := key + "=" + comments[key]
+		vorbis = binary.LittleEndian.AppendUint32(vorbis, uint32(len(field)))
+		vorbis = append(vorbis, field...)
+	}
+
+	// The Vorbis comment block is the last one
+	data = append(data, 0x84, byte(len(vorbis)>>16), byte(len(vorbis)>>8), byte(len(vorbis)))
+	return append(data, vorbis...)
+}
+
+// WAV returns a 16-bit mono WAV at 8kHz holding the given number of seconds of silence, with a
+// LIST chunk before the fmt chunk as some encoders write
+func WAV(seconds int) []byte {
+	format := binary.LittleEndian.AppendUint16(nil, 1)
+	format = binary.LittleEndian.AppendUint16(format, 1)
+	format = binary.LittleEndian.AppendUint32(format, 8000)
+	format = binary.LittleEndian.AppendUint32(format, 16000)
+	format = binary.LittleEndian.AppendUint16(format, 2)
+	format = binary.LittleEndian.AppendUint16(format, 16)
+
+	var chunks []byte
+	for _, chunk := range []struct {
+		id   string
+		data []byte
+	}{{"LIST", []byte("INFOtest")}, {"fmt ", format}, {"data", make([]byte, 16000*seconds)}} {
+		chunks = append(chunks, chunk.id...)
+		chunks = binary.LittleEndian.AppendUint32(chunks, uint32(len(chunk.data)))
+		chunks = append(chunks, chunk.data...)
+	}
+
+	data := []byte("RIFF")
+	data = binary.LittleEndian.AppendUint32(data, uint32(4+len(chunks)))
+	data = append(data, "WAVE"...)
+	return append(data, chunks...)
+}
+
+// M4A returns a minimal MP4 container whose tracks have the given sample formats, e.g. "mp4a"
+// for AAC or "alac"
+func M4A(formats ...string) []byte {
+	var traks [][]byte
+	traks = append(traks, mp4Atom("mvhd", make([]byte, 20)))
+	for _, format := range formats {
+		entry := binary.BigEndian.AppendUint32(nil, 16)
+		entry = append(entry, format...)
+		entry = append(entry, make([]byte, 8)...)
+		stsd := mp4Atom("stsd", make([]byte, 4), binary.BigEndian.AppendUint32(nil, 1), entry)
+		traks = append(traks, mp4Atom("trak", mp4Atom("tkhd", make([]byte, 20)), mp4Atom("mdia", mp4Atom("minf", mp4Atom("stbl", stsd)))))
+	}
+	return bytes.Join([][]byte{
+		mp4Atom("ftyp", []byte("M4A "), make([]byte, 4)),
+		mp4Atom("moov", traks...),
+		mp4Atom("mdat", make([]byte, 64)),
+	}, nil)
+}
+
+// mp4Atom returns an MP4 atom of the given type around the body
+func mp4Atom(kind string, body ...[]byte) []byte {
+	joined := bytes.Join(body, nil)
+	atom := binary.BigEndian.AppendUint32(nil, uint32(8+len(joined)))
+	atom = append(atom, kind...)
+	return append(atom, joined...)
+}
+
+// PNG returns a blank grey PNG image of the given size
+func PNG(width int, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+// WriteMP3 writes a minimal MP3 file tagged with the given text frames, creating its folder as
+// needed
+func WriteMP3(t *testing.T, path string, frames map[string]string) {
+	t.Helper()
+	WriteFile(t, path, MP3(frames, 413))
+}
+
+// WriteFile writes the data to the path, creating its folder as needed
+func WriteFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ListFiles returns the paths of all files under the folder, relative to it, slash separated
+// and sorted
+func ListFiles(t *testing.T, folder string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(folder, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Logger returns a text logger writing to w, or throwing everything away when w is io.Discard
+func Logger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, nil))
+}
+
+// CaptureStdout returns what the function prints to stdout
+func CaptureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	saved := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = saved }()
+	f()
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,17 @@
+package musicutils
+
+import (
+	"muxic/internal/testutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSHA256(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "abc.txt")
+	testutil.WriteFile(t, file, []byte("abc"))
+
+	hash, err := FileSHA256(file)
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; hash != want || err != nil {
+		t.Errorf("expected %s, got %s, %v", want, hash, err)
+	}
+}
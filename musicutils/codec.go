@@ -0,0 +1,115 @@
+package musicutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnknownCodec is returned when a file's audio codec can't be worked out
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// ReadCodec returns the audio codec of a music file: "mp3", "flac" or "pcm" from the extension,
+// and for .m4a files "aac" or "alac", read from the container since the extension is shared
+func ReadCodec(file string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(file), ".m4a") {
+		return readCodec(nil, file)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return readCodec(f, file)
+}
+
+// readCodec names the codec from the contents of a file with the given name; only .m4a files
+// need the contents
+func readCodec(r io.ReadSeeker, name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mp3":
+		return "mp3", nil
+	case ".flac":
+		return "flac", nil
+	case ".wav":
+		return "pcm", nil
+	case ".m4a":
+		return mp4Codec(r)
+	}
+	return "", ErrUnknownCodec
+}
+
+// mp4AtomPath leads from the top of an MP4 file to the sample description of its tracks
+var mp4AtomPath = []string{"moov", "trak", "mdia", "minf", "stbl", "stsd"}
+
+// mp4Codec finds the first audio sample description in the MP4 container and names its codec
+func mp4Codec(r io.ReadSeeker) (string, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	return findMP4Codec(r, 0, end, 0)
+}
+
+// findMP4Codec walks the atoms between start and end looking for the next atom on the path,
+// trying every trak since the first may not be the audio
+func findMP4Codec(r io.ReadSeeker, start int64, end int64, depth int) (string, error) {
+	header := make([]byte, 8)
+	for offset := start; offset+8 <= end; {
+		_, err := r.Seek(offset, io.SeekStart)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.ReadFull(r, header)
+		if err != nil {
+			return "", ErrUnknownCodec
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			// 64 bit size after the type
+			large := make([]byte, 8)
+			_, err = io.ReadFull(r, large)
+			if err != nil {
+				return "", ErrUnknownCodec
+			}
+			size = int64(binary.BigEndian.Uint64(large))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return "", ErrUnknownCodec
+		}
+
+		if string(header[4:8]) == mp4AtomPath[depth] {
+			if depth == len(mp4AtomPath)-1 {
+				// stsd: version and flags, entry count, then the first entry's size and format
+				entry := make([]byte, 16)
+				_, err = io.ReadFull(r, entry)
+				if err != nil {
+					return "", ErrUnknownCodec
+				}
+				switch string(entry[12:16]) {
+				case "alac":
+					return "alac", nil
+				case "mp4a":
+					return "aac", nil
+				}
+			} else {
+				codec, err := findMP4Codec(r, offset+headerSize, offset+size, depth+1)
+				if err == nil {
+					return codec, nil
+				}
+			}
+		}
+		offset += size
+	}
+	return "", ErrUnknownCodec
+}
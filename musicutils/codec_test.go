@@ -0,0 +1,47 @@
+package musicutils
+
+import (
+	"muxic/internal/testutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCodec(t *testing.T) {
+	folder := t.TempDir()
+	testutil.WriteFile(t, filepath.Join(folder, "lossy.m4a"), testutil.M4A("mp4a"))
+	testutil.WriteFile(t, filepath.Join(folder, "lossless.m4a"), testutil.M4A("avc1", "alac"))
+	testutil.WriteFile(t, filepath.Join(folder, "video.m4a"), testutil.M4A("avc1"))
+	testutil.WriteMP3(t, filepath.Join(folder, "song.MP3"), nil)
+
+	tests := []struct {
+		file  string
+		codec string
+		err   error
+	}{
+		{"lossy.m4a", "aac", nil},
+		{"lossless.m4a", "alac", nil},
+		{"video.m4a", "", ErrUnknownCodec},
+		{"song.MP3", "mp3", nil},
+	}
+	for _, test := range tests {
+		codec, err := ReadCodec(filepath.Join(folder, test.file))
+		if codec != test.codec || err != test.err {
+			t.Errorf("%s: expected %q, %v, got %q, %v", test.file, test.codec, test.err, codec, err)
+		}
+	}
+}
+
+func TestFilterCodec(t *testing.T) {
+	folder := t.TempDir()
+	lossy, lossless := filepath.Join(folder, "lossy.m4a"), filepath.Join(folder, "lossless.m4a")
+	testutil.WriteFile(t, lossy, testutil.M4A("mp4a"))
+	testutil.WriteFile(t, lossless, testutil.M4A("alac"))
+
+	filter := Filter{Codec: "alac", IncludeEmpty: true}
+	if !filter.Matches(lossless) {
+		t.Error("expected the ALAC file to pass")
+	}
+	if matches, reason := filter.Check(lossy); matches || reason != SkipFilteredByCodec {
+		t.Errorf("expected the AAC file to be filtered by codec, got %v %q", matches, reason)
+	}
+}
@@ -0,0 +1,90 @@
+package musicutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// DestinationName returns the path, relative to the target folder, that movemusic.CopyMusic
+// gives the file: "Artist/Album/01 - Title.ext" with useFolders, or
+// "Artist - Album - 01 - Title.ext" without. It reads the same tags and cleans them up the same
+// way, so a dry run can tell where a file would go without copying it. Files movemusic can't
+// copy return an error.
+func DestinationName(file string, useFolders bool) (string, error) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext != ".mp3" && ext != ".flac" && ext != ".wav" {
+		return "", fmt.Errorf("unsupported file type")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	artist := "Unknown"
+	album := "Unknown"
+	title := strings.TrimSuffix(filepath.Base(file), ext)
+	trackNumber := 1
+
+	m, err := tag.ReadFrom(f)
+	if err == nil {
+		if m.Artist() != "" {
+			artist = m.Artist()
+		}
+		if m.Album() != "" {
+			album = m.Album()
+		}
+		if m.Title() != "" {
+			title = m.Title()
+		}
+		trackNumber, _ = m.Track()
+	}
+
+	artist, album, title = cleanName(artist), cleanName(album), cleanName(title)
+
+	var name string
+	if useFolders {
+		name = filepath.Join(artist, album, fmt.Sprintf("%02d - %s%s", trackNumber, title, ext))
+	} else {
+		name = fmt.Sprintf("%s - %s - %02d - %s%s", artist, album, trackNumber, title, ext)
+	}
+
+	// movemusic falls back to the original name (with the extension added again) when the
+	// tags make the name too long
+	if len(name) > 255 {
+		name = filepath.Base(file) + ext
+	}
+	return name, nil
+}
+
+// cleanName tidies a tag value the way movemusic does for file and folder names: characters
+// that aren't allowed in paths become dashes, "feat." becomes "ft", "&" becomes "and",
+// anything outside printable ASCII is dropped and the result is title cased
+func cleanName(s string) string {
+	s = strings.Trim(s, " \t\n\r\"'")
+
+	s = strings.NewReplacer("/", "-", "\\", "-", ":", "-", "*", "-", "?", "-", "\"", "-", "<", "-", ">", "-", "|", "-").Replace(s)
+	s = strings.ReplaceAll(s, "  ", " ")
+
+	s = strings.ReplaceAll(s, "feat.", "ft")
+	s = strings.ReplaceAll(s, "Feat.", "ft")
+	s = strings.ReplaceAll(s, "Feat", "ft")
+	s = strings.ReplaceAll(s, "Featuring", "ft")
+	s = strings.ReplaceAll(s, "&", "and")
+
+	s = strings.Map(func(r rune) rune {
+		if r >= 32 && r <= 126 {
+			return r
+		}
+		return -1
+	}, s)
+
+	return cases.Title(language.English).String(s)
+}
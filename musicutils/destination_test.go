@@ -0,0 +1,52 @@
+package musicutils
+
+import (
+	"muxic/internal/testutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/punkscience/movemusic"
+)
+
+func TestDestinationNameMatchesMovemusic(t *testing.T) {
+	source := t.TempDir()
+	files := map[string][]byte{
+		"tagged.mp3":    testutil.MP3(testutil.Track("The Band", "First Album", "Opening", "1"), 413),
+		"feat.mp3":      testutil.MP3(testutil.Track("A feat. B & C", "Live: Vol 1/2", "what? \"now\"", "12/14"), 413),
+		"accents.flac":  testutil.MP3(testutil.Track("Émilie", "Ça va", "10cc's  song", "3"), 413),
+		"no number.mp3": testutil.MP3(map[string]string{"TPE1": "Artist", "TIT2": "Title"}, 413),
+		"untagged.mp3":  {0xff, 0xfb, 0x90, 0x00},
+		"SHOUTY.MP3":    {0xff, 0xfb, 0x90, 0x00},
+		"long.mp3":      testutil.MP3(testutil.Track(strings.Repeat("Artist ", 20), strings.Repeat("Album ", 20), strings.Repeat("Title ", 20), "1"), 413),
+	}
+
+	for name, data := range files {
+		file := filepath.Join(source, name)
+		testutil.WriteFile(t, file, data)
+
+		for _, useFolders := range []bool{true, false} {
+			target := t.TempDir()
+			want, err := movemusic.CopyMusic(file, target, useFolders)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			got, err := DestinationName(file, useFolders)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			} else if got = filepath.Join(target, got); got != want {
+				t.Errorf("%s (folders %v): got %q, movemusic placed it at %q", name, useFolders, got, want)
+			}
+		}
+	}
+}
+
+func TestDestinationNameUnsupported(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "song.m4a")
+	testutil.WriteFile(t, file, []byte("not really AAC"))
+
+	if name, err := DestinationName(file, true); err == nil {
+		t.Errorf("expected movemusic's unsupported file type error, got %q", name)
+	}
+}
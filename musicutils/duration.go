@@ -0,0 +1,147 @@
+package musicutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnknownDuration is returned when a file's play time can't be worked out from its headers
+var ErrUnknownDuration = errors.New("unknown duration")
+
+// ReadDuration works out the play time of a music file from its stream headers. Only FLAC and
+// WAV, whose headers state the length directly, are supported; anything else returns
+// ErrUnknownDuration.
+func ReadDuration(file string) (time.Duration, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return readDuration(f, file)
+}
+
+// readDuration works out the play time from the contents of a file with the given name
+func readDuration(r io.ReadSeeker, name string) (time.Duration, error) {
+	_, err := r.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".flac":
+		return flacDuration(r)
+	case ".wav":
+		return wavDuration(r)
+	}
+
+	return 0, ErrUnknownDuration
+}
+
+// flacDuration reads the total samples and sample rate from the FLAC STREAMINFO block, which
+// is always the first metadata block
+func flacDuration(r io.ReadSeeker) (time.Duration, error) {
+	header := make([]byte, 10)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, err
+	}
+
+	// Some taggers put an ID3v2 tag in front of the stream, skip over it
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+		_, err = r.Seek(10+size, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		_, err = io.ReadFull(r, header[:4])
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		_, err = r.Seek(4, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if string(header[:4]) != "fLaC" {
+		return 0, ErrUnknownDuration
+	}
+
+	// Block header (type + length) followed by the 34 byte STREAMINFO block
+	block := make([]byte, 4+34)
+	_, err = io.ReadFull(r, block)
+	if err != nil {
+		return 0, err
+	}
+	if block[0]&0x7f != 0 {
+		return 0, ErrUnknownDuration
+	}
+	info := block[4:]
+
+	sampleRate := uint64(info[10])<<12 | uint64(info[11])<<4 | uint64(info[12])>>4
+	totalSamples := uint64(info[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 || totalSamples == 0 {
+		return 0, ErrUnknownDuration
+	}
+
+	return time.Duration(totalSamples * uint64(time.Second) / sampleRate), nil
+}
+
+// wavDuration works the length out from the byte rate in the WAV fmt chunk and the size of the
+// data chunk
+func wavDuration(r io.ReadSeeker) (time.Duration, error) {
+	header := make([]byte, 12)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return 0, err
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return 0, ErrUnknownDuration
+	}
+
+	var byteRate uint64
+	chunk := make([]byte, 8)
+	for {
+		_, err = io.ReadFull(r, chunk)
+		if err != nil {
+			return 0, ErrUnknownDuration
+		}
+		id := string(chunk[:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return 0, ErrUnknownDuration
+			}
+			format := make([]byte, 16)
+			_, err = io.ReadFull(r, format)
+			if err != nil {
+				return 0, err
+			}
+			byteRate = uint64(binary.LittleEndian.Uint32(format[8:12]))
+			size -= 16
+
+		case "data":
+			// The fmt chunk has to come first for the length to be known
+			if byteRate == 0 {
+				return 0, ErrUnknownDuration
+			}
+			return time.Duration(uint64(size) * uint64(time.Second) / byteRate), nil
+		}
+
+		// Chunks are padded to an even length
+		_, err = r.Seek(size+size%2, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
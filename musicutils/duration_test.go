@@ -0,0 +1,44 @@
+package musicutils
+
+import (
+	"errors"
+	"muxic/internal/testutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadDuration(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"plain.flac":     testutil.FLAC(map[string]string{"ARTIST": "Artist"}, nil),
+		"id3 first.flac": testutil.FLAC(nil, testutil.Track("Artist", "Album", "Title", "1")),
+		"ten.wav":        testutil.WAV(10),
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		testutil.WriteFile(t, file, data)
+
+		got, err := ReadDuration(file)
+		if err != nil || got != 10*time.Second {
+			t.Errorf("%s: got %v, %v, want 10s", name, got, err)
+		}
+	}
+}
+
+func TestReadDurationUnknown(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"song.mp3":      testutil.MP3(nil, 413),
+		"not flac.flac": []byte("RIFF and other things"),
+		"no data.wav":   []byte("RIFF\x00\x00\x00\x00WAVE"),
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		testutil.WriteFile(t, file, data)
+
+		if got, err := ReadDuration(file); !errors.Is(err, ErrUnknownDuration) {
+			t.Errorf("%s: expected ErrUnknownDuration, got %v, %v", name, got, err)
+		}
+	}
+}
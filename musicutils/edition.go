@@ -0,0 +1,26 @@
+package musicutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// editionWords are the words that mark a bracketed or dashed album suffix as an edition note
+const editionWords = `deluxe|edition|remaster|remastered|bonus|expanded|anniversary|version|special|collector'?s`
+
+// editionSuffix matches one edition note at the end of an album name, either in brackets, as
+// in "(Deluxe Edition)" or "[Bonus Track Version]", or after a dash, as in "- Remastered 2011"
+var editionSuffix = regexp.MustCompile(`(?i)\s*(?:[(\[][^()\[\]]*\b(?:` + editionWords + `)\b[^()\[\]]*[)\]]|\s-\s[^-]*\b(?:` + editionWords + `)\b[^-]*)$`)
+
+// StripEdition returns the album name without the edition notes at its end, so "Album (Deluxe
+// Edition)", "Album (Remastered)" and "Album" all come back as "Album". Notes that don't name an
+// edition, like "(Live)" or "(Disc 1)", are kept.
+func StripEdition(album string) string {
+	for {
+		stripped := strings.TrimSpace(editionSuffix.ReplaceAllString(album, ""))
+		if stripped == album || stripped == "" {
+			return album
+		}
+		album = stripped
+	}
+}
@@ -0,0 +1,26 @@
+package musicutils
+
+import "testing"
+
+func TestStripEdition(t *testing.T) {
+	tests := map[string]string{
+		"Album":                                 "Album",
+		"Album (Deluxe Edition)":                "Album",
+		"Album (Remastered)":                    "Album",
+		"Album [Bonus Track Version]":           "Album",
+		"Album (2011 Remaster)":                 "Album",
+		"Album - Remastered 2011":               "Album",
+		"Album (Deluxe) [Remastered]":           "Album",
+		"Album (20th anniversary edition)":      "Album",
+		"Album (Live)":                          "Album (Live)",
+		"Album (Disc 1)":                        "Album (Disc 1)",
+		"Greatest Hits - Volume 2":              "Greatest Hits - Volume 2",
+		"(Deluxe Edition)":                      "(Deluxe Edition)",
+		"Live at the Apollo (Expanded Edition)": "Live at the Apollo",
+	}
+	for album, want := range tests {
+		if got := StripEdition(album); got != want {
+			t.Errorf("StripEdition(%q) = %q, expected %q", album, got, want)
+		}
+	}
+}
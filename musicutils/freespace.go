@@ -0,0 +1,6 @@
+package musicutils
+
+import "errors"
+
+// errFreeSpaceUnsupported is returned by FreeSpace where there's no way to ask the OS
+var errFreeSpaceUnsupported = errors.New("free space can't be checked on this platform")
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package musicutils
+
+// FreeSpace isn't supported here
+func FreeSpace(folder string) (uint64, error) {
+	return 0, errFreeSpaceUnsupported
+}
+
+// SameVolume can't tell volumes apart here, so it assumes they differ
+func SameVolume(a string, b string) bool {
+	return false
+}
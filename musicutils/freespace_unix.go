@@ -0,0 +1,38 @@
+//go:build linux || darwin
+
+package musicutils
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// FreeSpace returns the number of bytes available to the current user on the volume holding
+// the folder
+func FreeSpace(folder string) (uint64, error) {
+	var stat unix.Statfs_t
+	err := unix.Statfs(folder, &stat)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
+
+// SameVolume checks to see if both paths are on the same volume, so a move between them is
+// just a rename
+func SameVolume(a string, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+
+	aStat, aOK := aInfo.Sys().(*syscall.Stat_t)
+	bStat, bOK := bInfo.Sys().(*syscall.Stat_t)
+	return aOK && bOK && aStat.Dev == bStat.Dev
+}
@@ -0,0 +1,23 @@
+package musicutils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// HomeEnv is the environment variable that relocates muxic's state folder
+const HomeEnv = "MUXIC_HOME"
+
+// StateDir returns the folder muxic keeps its own state in (the fallback trash, watermarks and
+// the decision log): $MUXIC_HOME when it's set, otherwise ~/.muxic
+func StateDir() (string, error) {
+	if dir := os.Getenv(HomeEnv); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".muxic"), nil
+}
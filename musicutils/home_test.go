@@ -0,0 +1,38 @@
+package musicutils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStateDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	t.Setenv(HomeEnv, "")
+	dir, err := StateDir()
+	if err != nil || dir != filepath.Join(home, ".muxic") {
+		t.Errorf("expected ~/.muxic by default, got %q, %v", dir, err)
+	}
+
+	relocated := t.TempDir()
+	t.Setenv(HomeEnv, relocated)
+	dir, err = StateDir()
+	if err != nil || dir != relocated {
+		t.Errorf("expected $%s to relocate the state, got %q, %v", HomeEnv, dir, err)
+	}
+}
+
+func TestWatermarksFollowMuxicHome(t *testing.T) {
+	relocated := t.TempDir()
+	t.Setenv(HomeEnv, relocated)
+
+	file, err := watermarkFile(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, inside := RelPathInside(relocated, file); !inside {
+		t.Errorf("expected the watermark under %s, got %s", relocated, file)
+	}
+}
@@ -0,0 +1,66 @@
+package musicutils
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the file in a source folder listing glob patterns to leave out of scans
+const IgnoreFileName = ".muxicignore"
+
+// SkipMarkerName is a marker file; any folder containing it is left out of scans entirely
+const SkipMarkerName = ".muxicskip"
+
+// ReadIgnoreFile reads the patterns from the .muxicignore file in the folder. Blank lines and
+// lines starting with # are skipped. A missing file simply means there are no patterns.
+func ReadIgnoreFile(folder string) ([]string, error) {
+	f, err := os.Open(filepath.Join(folder, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// isIgnored checks a path, relative to the scan root, against gitignore-style glob patterns.
+// A pattern ending in / only matches folders. A pattern without a / matches the name at any
+// depth; one with a / is matched against the whole relative path.
+func isIgnored(relPath string, isDir bool, patterns []string) bool {
+	relPath = filepath.ToSlash(relPath)
+	name := path.Base(relPath)
+
+	for _, pattern := range patterns {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+
+		var matched bool
+		if strings.Contains(pattern, "/") {
+			matched, _ = path.Match(strings.TrimPrefix(pattern, "/"), relPath)
+		} else {
+			matched, _ = path.Match(pattern, name)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
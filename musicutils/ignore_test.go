@@ -0,0 +1,83 @@
+package musicutils
+
+import (
+	"context"
+	"muxic/internal/testutil"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnoreFileExcludesPaths(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Artist", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "Artist", "02.wav"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "__MACOSX", "Artist", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "archive", "keep", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "incomplete", "01.mp3"), nil)
+	testutil.WriteFile(t, filepath.Join(source, IgnoreFileName), []byte("# leftovers\n__MACOSX/\n\n*.wav\n/archive/keep\n"))
+
+	files := GetAllMusicFiles(context.Background(), source, ScanOptions{Exclude: []string{"incomplete"}})
+	if got, want := relPaths(source, files), []string{"Artist/01.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	tests := []struct {
+		path    string
+		isDir   bool
+		pattern string
+		ignored bool
+	}{
+		{"a/b/song.WAV", false, "*.WAV", true},
+		{"a/b/song.mp3", false, "*.wav", false},
+		{"a/tmp", true, "tmp/", true},
+		{"a/tmp", false, "tmp/", false},
+		{"a/b", true, "a/b", true},
+		{"c/a/b", true, "a/b", false},
+	}
+	for _, test := range tests {
+		if ignored := isIgnored(filepath.FromSlash(test.path), test.isDir, []string{test.pattern}); ignored != test.ignored {
+			t.Errorf("isIgnored(%q, %v, %q) = %v, expected %v", test.path, test.isDir, test.pattern, ignored, test.ignored)
+		}
+	}
+}
+
+func TestScanOptionsSkips(t *testing.T) {
+	root := t.TempDir()
+	scan := ScanOptions{SkipDirs: []string{filepath.Join(root, "organized")}, Exclude: []string{"*.wav", "tmp/"}}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		skips bool
+	}{
+		{"", true, false},
+		{"organized", true, true},
+		{"Artist/song.wav", false, true},
+		{"Artist/song.mp3", false, false},
+		{"Artist/tmp", true, true},
+		{"Artist/._song.mp3", false, true},
+		{".downloads", true, true},
+	}
+	for _, test := range tests {
+		path := filepath.Join(root, filepath.FromSlash(test.path))
+		if skips := scan.Skips(root, path, test.isDir); skips != test.skips {
+			t.Errorf("Skips(%q) = %v, expected %v", test.path, skips, test.skips)
+		}
+	}
+}
+
+func TestSkipMarkerExcludesFolder(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Incoming", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "Best Of", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "Best Of", "Disc 2", "01.mp3"), nil)
+	testutil.WriteFile(t, filepath.Join(source, "Best Of", SkipMarkerName), nil)
+
+	files := GetAllMusicFiles(context.Background(), source, ScanOptions{})
+	if got, want := relPaths(source, files), []string{"Incoming/01.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
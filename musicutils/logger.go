@@ -0,0 +1,37 @@
+package musicutils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// NewLogger returns a leveled logger writing to w in "text" or "json" format. Verbose turns on
+// debug messages. The handlers serialize writes, so the logger is safe to share between goroutines.
+func NewLogger(w io.Writer, verbose bool, format string) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q, expected text or json", format)
+}
+
+// NewRunID returns an identifier for a single run of muxic: the start time followed by a few
+// random hex digits, e.g. 20240615T142501-3f9a2c
+func NewRunID() string {
+	random := make([]byte, 3)
+	rand.Read(random)
+	return time.Now().Format("20060102T150405") + "-" + hex.EncodeToString(random)
+}
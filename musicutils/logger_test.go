@@ -0,0 +1,53 @@
+package musicutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := NewLogger(&out, false, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("Hidden")
+	logger.Info("Copying file", "file", "a.mp3")
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("expected a single JSON line, got %q: %v", out.String(), err)
+	}
+	if line["msg"] != "Copying file" || line["file"] != "a.mp3" || line["level"] != "INFO" {
+		t.Errorf("unexpected line %v", line)
+	}
+
+	out.Reset()
+	logger, err = NewLogger(&out, true, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("Skipping folder", "folder", "tmp")
+	if !strings.Contains(out.String(), `level=DEBUG msg="Skipping folder" folder=tmp`) {
+		t.Errorf("expected a debug line when verbose, got %q", out.String())
+	}
+}
+
+func TestNewLoggerUnknownFormat(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, false, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	first, second := NewRunID(), NewRunID()
+	if !regexp.MustCompile(`^\d{8}T\d{6}-[0-9a-f]{6}$`).MatchString(first) {
+		t.Errorf("unexpected run ID format %q", first)
+	}
+	if first == second {
+		t.Errorf("expected two runs to get different IDs, both got %q", first)
+	}
+}
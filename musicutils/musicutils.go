@@ -1,74 +1,289 @@
 package musicutils
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
-
-	"github.com/wtolson/go-taglib"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"time"
 )
 
-// GetAllMusicFiles returns a list of all music files in the specified folder
-func GetAllMusicFiles(folder string) []string {
-	fmt.Printf("Scanning all music files in folder %s ...\n", folder)
+// ScanOptions controls which files and folders GetAllMusicFiles walks into. The zero value
+// scans everything.
+type ScanOptions struct {
+	// SkipDirs are absolute folder paths whose subtrees are left out
+	SkipDirs []string
+
+	// Exclude holds glob patterns, in the same form as a .muxicignore file, for files and
+	// folders to leave out
+	Exclude []string
+
+	// IncludeHidden scans files and folders whose names start with a dot, which are
+	// otherwise skipped
+	IncludeHidden bool
+}
+
+// Skips checks to see if a scan of root leaves out the path: one of the SkipDirs, a hidden file
+// or folder unless IncludeHidden is set, a folder holding a .muxicskip marker, or a file or
+// folder matching an Exclude pattern. Only the path itself is checked, not the folders above it.
+// The root's .muxicignore file isn't read here; GetAllMusicFiles adds its patterns to Exclude.
+func (opts ScanOptions) Skips(root string, path string, isDir bool) bool {
+	if isDir && len(opts.SkipDirs) > 0 {
+		absPath, _ := filepath.Abs(path)
+		if slices.Contains(opts.SkipDirs, absPath) {
+			return true
+		}
+	}
+	if !opts.IncludeHidden && path != root && IsHidden(filepath.Base(path)) {
+		return true
+	}
+	if isDir && FileExists(filepath.Join(path, SkipMarkerName)) {
+		return true
+	}
+
+	if len(opts.Exclude) > 0 && path != root {
+		relPath, _ := filepath.Rel(root, path)
+		return isIgnored(relPath, isDir, opts.Exclude)
+	}
+	return false
+}
+
+// GetAllMusicFiles returns a list of all music files in the specified folder. Anything matched
+// by the scan options or the folder's .muxicignore file is left out, as is any folder holding a
+// .muxicskip marker. The scan stops early if the context is cancelled.
+func GetAllMusicFiles(ctx context.Context, folder string, opts ScanOptions) []string {
+	files, _ := ScanMusicFiles(ctx, folder, opts)
+	return files
+}
+
+// ScanMusicFiles works like GetAllMusicFiles, and also returns the paths that couldn't be read.
+// Unreadable files and folders are logged and passed over rather than ending the scan. The
+// folder may also be a single music file, which is returned on its own.
+func ScanMusicFiles(ctx context.Context, folder string, opts ScanOptions) ([]string, []string) {
+	slog.Info("Scanning all music files", "folder", folder)
+
+	stat, err := os.Stat(folder)
+	if err != nil {
+		slog.Warn("Error accessing path", "path", folder, "error", err)
+		return nil, []string{folder}
+	}
+	if !stat.IsDir() {
+		if !IsMusicFile(folder) {
+			slog.Warn("Not a music file", "file", folder)
+			return nil, nil
+		}
+		return []string{folder}, nil
+	}
+
+	patterns, err := ReadIgnoreFile(folder)
+	if err != nil {
+		slog.Warn("Error reading ignore file", "file", IgnoreFileName, "error", err)
+	}
+	opts.Exclude = append(patterns, opts.Exclude...)
+
 	var files []string
-	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+	var inaccessible []string
+	err = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			fmt.Printf("error accessing path %q: %v\n", path, err)
-			return err
+			slog.Warn("Error accessing path, skipping", "path", path, "error", err)
+			inaccessible = append(inaccessible, path)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if opts.Skips(folder, path, info.IsDir()) {
+			if info.IsDir() {
+				slog.Debug("Skipping folder", "folder", path)
+				return filepath.SkipDir
+			}
+			return nil
 		}
-		if !info.IsDir() && (strings.HasSuffix(info.Name(), ".mp3") ||
-			strings.HasSuffix(info.Name(), ".flac") ||
-			strings.HasSuffix(info.Name(), ".m4a") ||
-			strings.HasSuffix(info.Name(), ".wav")) {
+		if !info.IsDir() && IsMusicFile(info.Name()) {
 			files = append(files, path)
 
-			//fmt.Println("Found music file: ", path)
+			slog.Debug("Found music file", "file", path)
 		}
 		return nil
 	})
 	if err != nil {
-		fmt.Printf("error walking the path %q: %v\n", folder, err)
+		slog.Warn("Error walking the folder", "folder", folder, "error", err)
 	}
-	return files
+	if len(inaccessible) > 0 {
+		slog.Warn("Some paths could not be read", "folder", folder, "count", len(inaccessible))
+	}
+	return files, inaccessible
 }
 
-// GetTargetPathName returns the target path name for the file
-func GetTargetPathName(file string) string {
-	targetPath := ""
+// Filter describes which of the scanned music files should be kept. The zero value keeps
+// everything.
+type Filter struct {
+	// Pattern, when set, has to match the file's full path
+	Pattern *regexp.Regexp
 
-	// Get the music file tag info
-	if strings.HasSuffix(file, ".mp3") || strings.HasSuffix(file, ".flac") || strings.HasSuffix(file, ".wav") {
-		tag, err := taglib.Read(file)
-		if err != nil {
-			fmt.Printf("error opening file %q: %v\n", file, err)
-			return targetPath
+	// YearFrom and YearTo, when non-zero, bound the tag year (inclusive)
+	YearFrom int
+	YearTo   int
+
+	// IncludeUnknownYear keeps files with no year tag when a year bound is set
+	IncludeUnknownYear bool
+
+	// IncludeEmpty keeps zero-byte files, which are otherwise left out as failed downloads
+	IncludeEmpty bool
+
+	// ModifiedAfter, when set, leaves out files that haven't been modified since
+	ModifiedAfter time.Time
+
+	// Codec, when set, keeps only files in that audio codec, as named by ReadCodec
+	Codec string
+}
+
+// Reasons a Filter can give for leaving a file out
+const (
+	SkipFilteredByPattern = "filtered-by-pattern"
+	SkipFilteredByYear    = "filtered-by-year"
+	SkipUnknownYear       = "unknown-year"
+	SkipEmpty             = "empty"
+	SkipNotModified       = "not-modified-since"
+	SkipFilteredByCodec   = "filtered-by-codec"
+)
+
+// SkipInaccessible is the skip reason for a file or folder the scan couldn't read
+const SkipInaccessible = "inaccessible"
+
+// SkippedFile records a file that was left out and why
+type SkippedFile struct {
+	Path   string
+	Reason string
+}
+
+// Check returns whether the file passes the filter, and if it doesn't, the reason why. Tags are
+// only read when a year bound is set.
+func (f Filter) Check(file string) (bool, string) {
+	if !f.IncludeEmpty || !f.ModifiedAfter.IsZero() {
+		if stat, err := os.Stat(file); err == nil {
+			if !f.IncludeEmpty && stat.Size() == 0 {
+				return false, SkipEmpty
+			}
+			if !f.ModifiedAfter.IsZero() && !stat.ModTime().After(f.ModifiedAfter) {
+				return false, SkipNotModified
+			}
 		}
-		defer tag.Close()
+	}
+
+	if f.Pattern != nil && !f.Pattern.MatchString(file) {
+		return false, SkipFilteredByPattern
+	}
+
+	if f.Codec != "" {
+		codec, _ := ReadCodec(file)
+		if !strings.EqualFold(codec, f.Codec) {
+			return false, SkipFilteredByCodec
+		}
+	}
+
+	if f.YearFrom != 0 || f.YearTo != 0 {
+		info, _ := ReadTrackInfo(file)
+		if info.Year == 0 {
+			if !f.IncludeUnknownYear {
+				return false, SkipUnknownYear
+			}
+			return true, ""
+		}
+		if f.YearFrom != 0 && info.Year < f.YearFrom {
+			return false, SkipFilteredByYear
+		}
+		if f.YearTo != 0 && info.Year > f.YearTo {
+			return false, SkipFilteredByYear
+		}
+	}
+
+	return true, ""
+}
+
+// Matches checks to see if the file passes the filter
+func (f Filter) Matches(file string) bool {
+	matches, _ := f.Check(file)
+	return matches
+}
+
+// GetFilteredMusicFiles returns a list of all music files in the specified folder that pass
+// the filter, along with the files it rejected and why. Paths the scan couldn't read are
+// included in the rejections.
+func GetFilteredMusicFiles(ctx context.Context, folder string, scan ScanOptions, filter Filter) ([]string, []SkippedFile) {
+	allFiles, inaccessible := ScanMusicFiles(ctx, folder, scan)
+
+	var files []string
+	var skipped []SkippedFile
+	for _, path := range inaccessible {
+		skipped = append(skipped, SkippedFile{Path: path, Reason: SkipInaccessible})
+	}
+	for _, file := range allFiles {
+		if ctx.Err() != nil {
+			break
+		}
+		if matches, reason := filter.Check(file); matches {
+			files = append(files, file)
+		} else {
+			skipped = append(skipped, SkippedFile{Path: file, Reason: reason})
+		}
+	}
+	return files, skipped
+}
+
+// IsHidden checks to see if a file or folder name is hidden, i.e. starts with a dot. This also
+// covers the ._ resource fork files macOS leaves on other filesystems.
+func IsHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
 
-		// Format each string in proper title format
-		converter := cases.Title(language.English)
-		artist := converter.String(tag.Artist())
-		album := converter.String(tag.Album())
-		title := converter.String(tag.Title())
-		track := fmt.Sprintf("%d", tag.Track())
+// IsMusicFile checks to see if the file name has one of the supported music extensions,
+// ignoring case so that .MP3 and .Flac files are found too
+func IsMusicFile(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".mp3") ||
+		strings.HasSuffix(name, ".flac") ||
+		strings.HasSuffix(name, ".m4a") ||
+		strings.HasSuffix(name, ".wav")
+}
 
-		// Retrieve the desired tag information, e.g., tag.Title(), tag.Artist(), etc.
-		targetPath = fmt.Sprintf("%s/%s/%s - %s", artist, album, track, title)
-		targetPath = targetPath + filepath.Ext(file)
+// RelPathInside returns the file's path relative to the root folder. The second result is false
+// when the file isn't inside the root; the check goes by whole path components, so a sibling
+// like /music/lib2 is never taken to be inside /music/lib.
+func RelPathInside(root string, file string) (string, bool) {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// IsSubPath checks to see if child is a folder strictly inside parent
+func IsSubPath(parent string, child string) (bool, error) {
+	absParent, err := filepath.Abs(parent)
+	if err != nil {
+		return false, err
+	}
+	absChild, err := filepath.Abs(child)
+	if err != nil {
+		return false, err
+	}
 
-		// Return the target path name
-		return targetPath
-	} else {
-		log.Println("Unsupported file type with extension: ", filepath.Ext(file))
+	rel, err := filepath.Rel(absParent, absChild)
+	if err != nil {
+		// Different volumes can never be nested
+		return false, nil
 	}
 
-	return targetPath
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
 }
 
 // FileExists checks to see if the file exists
@@ -79,35 +294,56 @@ func FileExists(file string) bool {
 	return true
 }
 
-// CopyFile copies the file from the source to the target
-func CopyFile(source string, target string) {
+// CopyFile copies the file from the source to the target. The data is written to a temporary
+// .part file next to the target and only renamed into place once the copy has finished, so an
+// interrupted or cancelled copy never leaves a partial file under the real name.
+func CopyFile(ctx context.Context, source string, target string) error {
 	input, err := os.Open(source)
 	if err != nil {
-		log.Println("Error opening source file: ", source)
-		panic(err)
+		return fmt.Errorf("error opening source file: %v", err)
 	}
+	defer input.Close()
 
 	// Create the target path
 	err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
 	if err != nil {
-		log.Println("Error creating target path: ", err)
-		panic(err)
+		return fmt.Errorf("error creating target path: %v", err)
 	}
 
-	output, err := os.Create(target)
+	// Keeping the temp file in the target folder means the rename never crosses devices
+	partName := target + ".part"
+	output, err := os.OpenFile(partName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
 	if err != nil {
-		log.Println("Error creating target file: ", err)
-		panic(err)
+		return fmt.Errorf("error creating target file: %v", err)
 	}
-	defer output.Close()
 
-	_, err = io.Copy(output, input)
+	_, err = io.Copy(output, &contextReader{ctx: ctx, r: input})
+	closeErr := output.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partName, target)
+	}
 	if err != nil {
-		log.Println("Error copying file: ", err)
-		panic(err)
+		os.Remove(partName)
+		return fmt.Errorf("error copying file: %w", err)
 	}
 
-	input.Close()
+	return nil
+}
+
+// contextReader is a reader that stops with the context's error once it is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
 }
 
 // Check if a folder is empty
@@ -127,10 +363,10 @@ func IsDirEmpty(name string) (bool, error) {
 
 func DeleteFile(file string) {
 	// If this flag is set, delete the source file
-	fmt.Println("Deleting source file: ", file)
+	slog.Debug("Deleting source file", "file", file)
 	err := os.Remove(file)
 	if err != nil {
-		log.Println("Error deleting source file: ", err)
+		slog.Error("Error deleting source file", "file", file, "error", err)
 		return
 	}
 
@@ -140,10 +376,10 @@ func DeleteFile(file string) {
 	empty, err := IsDirEmpty(dir)
 	if err != nil {
 		if empty {
-			fmt.Println("Deleting empty source folder: ", dir)
+			slog.Debug("Deleting empty source folder", "folder", dir)
 			err = os.Remove(dir)
 			if err != nil {
-				log.Println("Error deleting source folder: ", err)
+				slog.Error("Error deleting source folder", "folder", dir, "error", err)
 				return
 			}
 
@@ -153,10 +389,10 @@ func DeleteFile(file string) {
 
 			if err != nil {
 				if empty {
-					fmt.Println("Deleting empty source folder: ", dir)
+					slog.Debug("Deleting empty source folder", "folder", dir)
 					err = os.Remove(dir)
 					if err != nil {
-						log.Println("Error deleting source artist folder: ", err)
+						slog.Error("Error deleting source artist folder", "folder", dir, "error", err)
 						return
 					}
 				}
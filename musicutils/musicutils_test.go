@@ -0,0 +1,267 @@
+package musicutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// relPaths returns the files relative to the folder, with forward slashes
+func relPaths(folder string, files []string) []string {
+	rel := make([]string, 0, len(files))
+	for _, file := range files {
+		path, _ := filepath.Rel(folder, file)
+		rel = append(rel, filepath.ToSlash(path))
+	}
+	slices.Sort(rel)
+	return rel
+}
+
+func TestFilterPatternSelectsSubtree(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Artist", "Album", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "incoming", "new", "02.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "incoming.mp3"), nil)
+
+	filter := Filter{Pattern: regexp.MustCompile(`[/\\]incoming[/\\]`)}
+	files, skipped := GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, filter)
+
+	if got, want := relPaths(source, files), []string{"incoming/new/02.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(skipped) != 2 || skipped[0].Reason != SkipFilteredByPattern {
+		t.Errorf("expected two files filtered by the pattern, got %v", skipped)
+	}
+}
+
+func TestEmptyFilterMatchesEverything(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "a.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "b", "c.flac"), nil)
+	testutil.WriteFile(t, filepath.Join(source, "notes.txt"), []byte("not music"))
+
+	files, _ := GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, Filter{})
+
+	if got, want := relPaths(source, files), []string{"a.mp3", "b/c.flac"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCancelledScanStops(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "01.mp3"), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if files := GetAllMusicFiles(ctx, source, ScanOptions{}); len(files) != 0 {
+		t.Errorf("expected a cancelled scan to find nothing, got %v", files)
+	}
+}
+
+func TestCancelledCopyLeavesNothing(t *testing.T) {
+	folder := t.TempDir()
+	source, target := filepath.Join(folder, "in.mp3"), filepath.Join(folder, "out", "out.mp3")
+	testutil.WriteMP3(t, source, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := CopyFile(ctx, source, target)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the copy to be cancelled, got %v", err)
+	}
+	if FileExists(target) || FileExists(target+".part") {
+		t.Error("expected no file left behind by the cancelled copy")
+	}
+}
+
+func TestIsSubPath(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		parent string
+		child  string
+		nested bool
+	}{
+		{root, filepath.Join(root, "organized"), true},
+		{root, filepath.Join(root, "a", "b"), true},
+		{root, root, false},
+		{filepath.Join(root, "organized"), root, false},
+		{filepath.Join(root, "lib"), filepath.Join(root, "lib2"), false},
+	}
+	for _, test := range tests {
+		nested, err := IsSubPath(test.parent, test.child)
+		if err != nil || nested != test.nested {
+			t.Errorf("IsSubPath(%q, %q) = %v, %v, expected %v", test.parent, test.child, nested, err, test.nested)
+		}
+	}
+}
+
+func TestSkipDirsLeavesOutSubtree(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "new.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "organized", "Artist", "old.mp3"), nil)
+
+	files := GetAllMusicFiles(context.Background(), source, ScanOptions{SkipDirs: []string{filepath.Join(source, "organized")}})
+	if got, want := relPaths(source, files), []string{"new.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFilterYearRange(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "1985.mp3"), map[string]string{"TIT2": "In", "TYER": "1985"})
+	testutil.WriteMP3(t, filepath.Join(source, "1999.mp3"), map[string]string{"TIT2": "Out", "TYER": "1999"})
+	testutil.WriteMP3(t, filepath.Join(source, "unknown.mp3"), map[string]string{"TIT2": "Unknown"})
+
+	tests := []struct {
+		filter Filter
+		want   []string
+	}{
+		{Filter{}, []string{"1985.mp3", "1999.mp3", "unknown.mp3"}},
+		{Filter{YearFrom: 1980, YearTo: 1989}, []string{"1985.mp3"}},
+		{Filter{YearFrom: 1990}, []string{"1999.mp3"}},
+		{Filter{YearTo: 1989, IncludeUnknownYear: true}, []string{"1985.mp3", "unknown.mp3"}},
+	}
+	for _, test := range tests {
+		files, _ := GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, test.filter)
+		if got := relPaths(source, files); !slices.Equal(got, test.want) {
+			t.Errorf("%+v: expected %v, got %v", test.filter, test.want, got)
+		}
+	}
+
+	_, skipped := GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, Filter{YearTo: 1989})
+	reasons := make(map[string]string)
+	for _, file := range skipped {
+		reasons[filepath.Base(file.Path)] = file.Reason
+	}
+	if reasons["1999.mp3"] != SkipFilteredByYear || reasons["unknown.mp3"] != SkipUnknownYear || len(reasons) != 2 {
+		t.Errorf("expected 1999.mp3 filtered by year and unknown.mp3 for its unknown year, got %v", reasons)
+	}
+}
+
+func TestCopyFileLeavesNoPartFile(t *testing.T) {
+	folder := t.TempDir()
+	source, target := filepath.Join(folder, "in.mp3"), filepath.Join(folder, "out", "out.mp3")
+	testutil.WriteMP3(t, source, map[string]string{"TIT2": "Song"})
+
+	err := CopyFile(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := os.ReadFile(source)
+	got, err := os.ReadFile(target)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("expected the copy to match the source, got %v", err)
+	}
+	if FileExists(target + ".part") {
+		t.Error("expected no .part file after the copy")
+	}
+}
+
+func TestIsMusicFileIgnoresCase(t *testing.T) {
+	for _, name := range []string{"a.mp3", "a.MP3", "a.Flac", "a.M4A", "a.wav"} {
+		if !IsMusicFile(name) {
+			t.Errorf("expected %s to be a music file", name)
+		}
+	}
+	for _, name := range []string{"a.mp3.txt", "cover.JPG", "mp3"} {
+		if IsMusicFile(name) {
+			t.Errorf("expected %s not to be a music file", name)
+		}
+	}
+}
+
+func TestScanPassesOverUnreadableFolders(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("needs a platform and user that folder permissions apply to")
+	}
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "open", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "locked", "01.mp3"), nil)
+	locked := filepath.Join(source, "locked")
+	err := os.Chmod(locked, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	files, inaccessible := ScanMusicFiles(context.Background(), source, ScanOptions{})
+	if got, want := relPaths(source, files), []string{"open/01.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !slices.Equal(inaccessible, []string{locked}) {
+		t.Errorf("expected %s to be reported, got %v", locked, inaccessible)
+	}
+}
+
+func TestScanReportsMissingSource(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	files, skipped := GetFilteredMusicFiles(context.Background(), missing, ScanOptions{}, Filter{})
+	if len(files) != 0 || len(skipped) != 1 || skipped[0].Reason != SkipInaccessible {
+		t.Errorf("expected the missing source to be reported, got %v, %v", files, skipped)
+	}
+}
+
+func TestRelPathInside(t *testing.T) {
+	root := filepath.FromSlash("/music/lib")
+	tests := []struct {
+		file   string
+		rel    string
+		inside bool
+	}{
+		{"/music/lib/Artist/song.mp3", "Artist/song.mp3", true},
+		{"/music/lib/..odd/song.mp3", "..odd/song.mp3", true},
+		{"/music/lib2/song.mp3", "", false},
+		{"/music/song.mp3", "", false},
+		{"/music", "", false},
+	}
+	for _, test := range tests {
+		rel, inside := RelPathInside(root, filepath.FromSlash(test.file))
+		if filepath.ToSlash(rel) != test.rel || inside != test.inside {
+			t.Errorf("RelPathInside(%q) = %q, %v, expected %q, %v", test.file, rel, inside, test.rel, test.inside)
+		}
+	}
+}
+
+func TestScanSkipsHiddenFiles(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Album", "01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "Album", "._01.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, ".hidden", "02.mp3"), nil)
+
+	files := GetAllMusicFiles(context.Background(), source, ScanOptions{})
+	if got, want := relPaths(source, files), []string{"Album/01.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	files = GetAllMusicFiles(context.Background(), source, ScanOptions{IncludeHidden: true})
+	if got, want := relPaths(source, files), []string{".hidden/02.mp3", "Album/._01.mp3", "Album/01.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("with hidden files included, expected %v, got %v", want, got)
+	}
+}
+
+func TestFilterSkipsEmptyFiles(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), nil)
+	testutil.WriteFile(t, filepath.Join(source, "failed.mp3"), nil)
+
+	files, skipped := GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, Filter{})
+	if got, want := relPaths(source, files), []string{"song.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(skipped) != 1 || filepath.Base(skipped[0].Path) != "failed.mp3" || skipped[0].Reason != SkipEmpty {
+		t.Errorf("expected the empty file reported, got %v", skipped)
+	}
+
+	files, _ = GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, Filter{IncludeEmpty: true})
+	if len(files) != 2 {
+		t.Errorf("expected the empty file kept with IncludeEmpty, got %v", files)
+	}
+}
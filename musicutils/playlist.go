@@ -0,0 +1,32 @@
+package musicutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// PlaylistEntry is a single track in an extended M3U playlist
+type PlaylistEntry struct {
+	// Path is written as is, so it should already be relative or absolute as wanted
+	Path string
+
+	// Seconds is the track length, or -1 when it isn't known
+	Seconds int
+
+	Artist string
+	Title  string
+}
+
+// WritePlaylist writes the entries as an extended M3U (M3U8) playlist
+func WritePlaylist(w io.Writer, entries []PlaylistEntry) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, entry := range entries {
+		fmt.Fprintf(bw, "#EXTINF:%d,%s - %s\n", entry.Seconds, entry.Artist, entry.Title)
+		fmt.Fprintln(bw, entry.Path)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,27 @@
+package musicutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePlaylist(t *testing.T) {
+	var out strings.Builder
+	err := WritePlaylist(&out, []PlaylistEntry{
+		{Path: "Artist/Album/01 - One.flac", Seconds: 10, Artist: "Artist", Title: "One"},
+		{Path: "/music/Other/Album/02 - Two.mp3", Seconds: -1, Artist: "Other", Title: "Two"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `#EXTM3U
+#EXTINF:10,Artist - One
+Artist/Album/01 - One.flac
+#EXTINF:-1,Other - Two
+/music/Other/Album/02 - Two.mp3
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
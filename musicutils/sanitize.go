@@ -0,0 +1,22 @@
+package musicutils
+
+import (
+	"strings"
+)
+
+// SanitizeName makes a single file or folder name safe on every filesystem muxic writes to.
+// Characters Windows doesn't allow become hyphens, and trailing spaces and dots are dropped.
+func SanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"|?*\/`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+
+	name = strings.TrimRight(name, " .")
+	if name == "" {
+		return "-"
+	}
+	return name
+}
@@ -0,0 +1,20 @@
+package musicutils
+
+import "testing"
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"Artist":       "Artist",
+		"AC/DC":        "AC-DC",
+		`What? "Now"`:  "What- -Now-",
+		"Tab\there":    "Tab-here",
+		"Trailing. . ": "Trailing",
+		"...":          "-",
+		"Ünïcödé Näme": "Ünïcödé Näme",
+	}
+	for name, want := range tests {
+		if got := SanitizeName(name); got != want {
+			t.Errorf("SanitizeName(%q) = %q, expected %q", name, got, want)
+		}
+	}
+}
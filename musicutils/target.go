@@ -0,0 +1,18 @@
+package musicutils
+
+import (
+	"regexp"
+	"time"
+)
+
+// dateToken matches {date:LAYOUT} in a target folder, where LAYOUT is a Go time layout
+var dateToken = regexp.MustCompile(`\{date:([^}]*)\}`)
+
+// ExpandTarget replaces every {date:LAYOUT} token in the target folder with the time formatted
+// using that layout, e.g. {date:2006-01-02} becomes the day's date
+func ExpandTarget(target string, t time.Time) string {
+	return dateToken.ReplaceAllStringFunc(target, func(token string) string {
+		layout := dateToken.FindStringSubmatch(token)[1]
+		return t.Format(layout)
+	})
+}
@@ -0,0 +1,21 @@
+package musicutils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandTarget(t *testing.T) {
+	now := time.Date(2024, 6, 15, 9, 30, 0, 0, time.UTC)
+	tests := map[string]string{
+		"/music":                          "/music",
+		"/music/{date:2006-01-02}":        "/music/2024-06-15",
+		"/music/{date:2006}/{date:01-02}": "/music/2024/06-15",
+		"/music/{date:15h04}-import":      "/music/09h30-import",
+	}
+	for target, want := range tests {
+		if got := ExpandTarget(target, now); got != want {
+			t.Errorf("ExpandTarget(%q) = %q, expected %q", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package musicutils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+// TrackInfo holds the tag information muxic cares about for a single music file
+type TrackInfo struct {
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Title       string
+	TrackNumber int
+	TotalTracks int
+	DiscNumber  int
+	Year        int
+
+	// Duration is zero when it can't be worked out from the file
+	Duration time.Duration
+
+	// Codec is the audio codec, e.g. "aac" or "alac" for .m4a files, empty when unknown
+	Codec string
+
+	// TagFormat is the tag format the values were read from, e.g. "ID3v2.3" or "VORBIS"
+	TagFormat string
+
+	// Missing lists the fields ("artist", "album" and "title") that had no tag and took their
+	// default
+	Missing []string
+}
+
+// ReadTrackInfo reads the tag information from a music file. Missing values fall back to the
+// same defaults movemusic uses when naming files: "Unknown" for the artist and album, and the
+// file name for the title. A FLAC file with an ID3 tag in front of its Vorbis comments reads
+// as ID3, the same tags movemusic names it from, so reports agree with where the file lands.
+func ReadTrackInfo(file string) (TrackInfo, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return defaultTrackInfo(file), err
+	}
+	defer f.Close()
+	return ReadTrackInfoFrom(f, file)
+}
+
+// defaultTrackInfo returns the values used for a file with no tags
+func defaultTrackInfo(name string) TrackInfo {
+	return TrackInfo{
+		Artist: "Unknown",
+		Album:  "Unknown",
+		Title:  strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)),
+
+		// Until the tags are read, nothing has been found
+		Missing: []string{"artist", "album", "title"},
+	}
+}
+
+// ReadTrackInfoFrom reads the tag information like ReadTrackInfo, but from the contents of a
+// music file, e.g. an archive entry held in memory. The name is only used for its extension
+// and as the fallback title.
+func ReadTrackInfoFrom(r io.ReadSeeker, name string) (TrackInfo, error) {
+	info := defaultTrackInfo(name)
+
+	// The length comes from the stream headers, so it's known even for untagged files
+	info.Duration, _ = readDuration(r, name)
+	info.Codec, _ = readCodec(r, name)
+
+	_, err := r.Seek(0, io.SeekStart)
+	if err != nil {
+		return info, err
+	}
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		return info, err
+	}
+
+	info.Missing = nil
+	if m.Artist() != "" {
+		info.Artist = m.Artist()
+	} else {
+		info.Missing = append(info.Missing, "artist")
+	}
+
+	if m.Album() != "" {
+		info.Album = m.Album()
+	} else {
+		info.Missing = append(info.Missing, "album")
+	}
+
+	if m.Title() != "" {
+		info.Title = m.Title()
+	} else {
+		info.Missing = append(info.Missing, "title")
+	}
+
+	info.AlbumArtist = m.AlbumArtist()
+	info.TrackNumber, info.TotalTracks = m.Track()
+	info.DiscNumber, _ = m.Disc()
+	info.Year = m.Year()
+	info.TagFormat = string(m.Format())
+
+	return info, nil
+}
+
+// AlbumKey returns the artist and album used to group tracks into albums, preferring the
+// album artist when it is set
+func (t TrackInfo) AlbumKey() (string, string) {
+	if t.AlbumArtist != "" {
+		return t.AlbumArtist, t.Album
+	}
+	return t.Artist, t.Album
+}
+
+// BucketLetter returns the single-character folder an artist is filed under: the first letter
+// of the artist folder name movemusic would use, for A to Z, or # for anything else. So 'Til
+// Tuesday goes under T and Émilie under M, next to their folders.
+func BucketLetter(artist string) string {
+	artist = cleanName(artist)
+	if artist == "" {
+		return "#"
+	}
+	first := strings.ToUpper(artist[:1])
+	if first < "A" || first > "Z" {
+		return "#"
+	}
+	return first
+}
@@ -0,0 +1,112 @@
+package musicutils
+
+import (
+	"muxic/internal/testutil"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestReadTrackInfo(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "song.mp3")
+	frames := testutil.Track("Artist", "Album", "Song", "3/12")
+	frames["TPE2"] = "Various Artists"
+	frames["TPOS"] = "2"
+	frames["TYER"] = "1994"
+	testutil.WriteMP3(t, file, frames)
+
+	info, err := ReadTrackInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TrackInfo{
+		Artist:      "Artist",
+		AlbumArtist: "Various Artists",
+		Album:       "Album",
+		Title:       "Song",
+		TrackNumber: 3,
+		TotalTracks: 12,
+		DiscNumber:  2,
+		Year:        1994,
+		Codec:       "mp3",
+		TagFormat:   "ID3v2.3",
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, expected %+v", info, want)
+	}
+	if artist, album := info.AlbumKey(); artist != "Various Artists" || album != "Album" {
+		t.Errorf("expected the album artist to group the album, got %q, %q", artist, album)
+	}
+}
+
+func TestReadTrackInfoDefaults(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "untitled.mp3")
+	testutil.WriteMP3(t, file, map[string]string{"TIT2": ""})
+
+	info, err := ReadTrackInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Artist != "Unknown" || info.Album != "Unknown" || info.Title != "untitled" {
+		t.Errorf("expected movemusic's defaults, got %+v", info)
+	}
+	if !slices.Equal(info.Missing, []string{"artist", "album", "title"}) {
+		t.Errorf("expected every field reported missing, got %v", info.Missing)
+	}
+	if artist, _ := info.AlbumKey(); artist != "Unknown" {
+		t.Errorf("expected the artist to group the album, got %q", artist)
+	}
+}
+
+func TestBucketLetter(t *testing.T) {
+	tests := []struct {
+		artist string
+		bucket string
+	}{
+		{"Beatles", "B"},
+		{"beck", "B"},
+		{"10cc", "#"},
+		{"2Pac", "#"},
+		{"'Til Tuesday", "T"},
+		{"\"Weird Al\" Yankovic", "W"},
+		{"  Air", "A"},
+		{"Émilie Simon", "M"},
+		{"Björk", "B"},
+		{"!!!", "#"},
+		{"", "#"},
+		{"Ω", "#"},
+	}
+	for _, test := range tests {
+		if bucket := BucketLetter(test.artist); bucket != test.bucket {
+			t.Errorf("%q: expected %q, got %q", test.artist, test.bucket, bucket)
+		}
+	}
+}
+
+func TestReadTrackInfoAgreesWithDestination(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "track.flac")
+	testutil.WriteFile(t, file, testutil.FLAC(
+		map[string]string{"ARTIST": "Comment Artist", "ALBUM": "Comment Album", "TITLE": "Comment Title", "TRACKNUMBER": "2"},
+		testutil.Track("Tag Artist", "Tag Album", "Tag Title", "1"),
+	))
+
+	info, err := ReadTrackInfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Artist != "Tag Artist" || info.Album != "Tag Album" || info.Title != "Tag Title" || info.TrackNumber != 1 {
+		t.Errorf("expected the ID3 values movemusic names the file from, got %+v", info)
+	}
+	if info.TagFormat != "ID3v2.3" {
+		t.Errorf("expected the ID3v2.3 tag format, got %q", info.TagFormat)
+	}
+
+	name, err := DestinationName(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(info.Artist, info.Album, "01 - "+info.Title+".flac"); name != want {
+		t.Errorf("expected the destination %q to match the tags read, got %q", want, name)
+	}
+}
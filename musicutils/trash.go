@@ -0,0 +1,68 @@
+package musicutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errNoNativeTrash is returned by nativeTrash when the platform has no trash we can use
+var errNoNativeTrash = errors.New("no native trash available")
+
+// TrashFile moves the file to the user's trash (or recycle bin) instead of deleting it, so that
+// mistakes can be recovered. When there is no usable native trash the file goes to the trash
+// folder under StateDir instead.
+func TrashFile(file string) error {
+	err := nativeTrash(file)
+	if err == errNoNativeTrash {
+		return fallbackTrash(file)
+	}
+	return err
+}
+
+// fallbackTrash moves the file into muxic's own trash folder
+func fallbackTrash(file string) error {
+	state, err := StateDir()
+	if err != nil {
+		return err
+	}
+
+	trashDir := filepath.Join(state, "trash")
+	err = os.MkdirAll(trashDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return moveFile(file, uniqueName(trashDir, filepath.Base(file)))
+}
+
+// uniqueName returns a path for the name inside the folder that isn't taken yet, adding a
+// number before the extension when needed
+func uniqueName(folder string, name string) string {
+	target := filepath.Join(folder, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 2; FileExists(target); i++ {
+		target = filepath.Join(folder, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+	return target
+}
+
+// moveFile renames the file, falling back to a copy and delete when the rename can't be done
+// (e.g. across devices)
+func moveFile(source string, target string) error {
+	err := os.Rename(source, target)
+	if err == nil {
+		return nil
+	}
+
+	err = CopyFile(context.Background(), source, target)
+	if err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
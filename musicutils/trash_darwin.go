@@ -0,0 +1,21 @@
+package musicutils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// nativeTrash moves the file into the user's ~/.Trash folder
+func nativeTrash(file string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return errNoNativeTrash
+	}
+
+	trashDir := filepath.Join(home, ".Trash")
+	if _, err := os.Stat(trashDir); err != nil {
+		return errNoNativeTrash
+	}
+
+	return moveFile(file, uniqueName(trashDir, filepath.Base(file)))
+}
@@ -0,0 +1,57 @@
+package musicutils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nativeTrash moves the file into the freedesktop.org home trash, writing the .trashinfo file
+// file managers use to restore it
+func nativeTrash(file string) error {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return errNoNativeTrash
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	filesDir := filepath.Join(dataHome, "Trash", "files")
+	infoDir := filepath.Join(dataHome, "Trash", "info")
+	if os.MkdirAll(filesDir, 0700) != nil || os.MkdirAll(infoDir, 0700) != nil {
+		return errNoNativeTrash
+	}
+
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	// Pick a free name in the trash, the info file shares it
+	target := uniqueName(filesDir, filepath.Base(file))
+	name := filepath.Base(target)
+	infoFile := filepath.Join(infoDir, name+".trashinfo")
+
+	// Escape each path segment but keep the separators
+	segments := strings.Split(absFile, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", strings.Join(segments, "/"), time.Now().Format("2006-01-02T15:04:05"))
+	err = os.WriteFile(infoFile, []byte(info), 0600)
+	if err != nil {
+		return err
+	}
+
+	err = moveFile(file, target)
+	if err != nil {
+		os.Remove(infoFile)
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+package musicutils
+
+import (
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrashFileWritesTrashInfo(t *testing.T) {
+	dataHome, source := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	file := filepath.Join(source, "my song.mp3")
+	testutil.WriteFile(t, file, []byte("music"))
+	err := TrashFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if FileExists(file) {
+		t.Error("expected the file to be gone from the source")
+	}
+	if !FileExists(filepath.Join(dataHome, "Trash", "files", "my song.mp3")) {
+		t.Error("expected the file in the trash")
+	}
+	info, err := os.ReadFile(filepath.Join(dataHome, "Trash", "info", "my song.mp3.trashinfo"))
+	if err != nil || !strings.Contains(string(info), "Path="+filepath.Join(source, "my%20song.mp3")+"\n") {
+		t.Errorf("expected the original path in the trash info, got %q, %v", info, err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package musicutils
+
+// nativeTrash isn't supported here (the Windows recycle bin needs the shell API), so files go
+// to the fallback trash folder
+func nativeTrash(file string) error {
+	return errNoNativeTrash
+}
@@ -0,0 +1,33 @@
+package musicutils
+
+import (
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFallbackTrashKeepsBothCopies(t *testing.T) {
+	home, source := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	for _, folder := range []string{"a", "b"} {
+		file := filepath.Join(source, folder, "song.mp3")
+		testutil.WriteFile(t, file, []byte(folder))
+		err := fallbackTrash(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if FileExists(file) {
+			t.Errorf("expected %s to be gone from the source", file)
+		}
+	}
+
+	for name, want := range map[string]string{"song.mp3": "a", "song (2).mp3": "b"} {
+		data, err := os.ReadFile(filepath.Join(home, ".muxic", "trash", name))
+		if err != nil || string(data) != want {
+			t.Errorf("expected %s in the trash holding %q, got %q, %v", name, want, data, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package musicutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watermarkFile returns the file in StateDir's watermarks folder holding the watermark for the
+// pair of folders, named after a hash of their absolute paths
+func watermarkFile(source string, target string) (string, error) {
+	state, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(absSource + "\x00" + absTarget))
+	return filepath.Join(state, "watermarks", hex.EncodeToString(hash[:8])), nil
+}
+
+// ReadWatermark returns the time recorded by the last WriteWatermark for the source and target
+// folders, or the zero time if there isn't one
+func ReadWatermark(source string, target string) (time.Time, error) {
+	file, err := watermarkFile(source, target)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+}
+
+// WriteWatermark records the time for the source and target folders
+func WriteWatermark(source string, target string, t time.Time) error {
+	file, err := watermarkFile(source, target)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, []byte(t.Format(time.RFC3339Nano)+"\n"), 0644)
+}
@@ -0,0 +1,57 @@
+package musicutils
+
+import (
+	"context"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatermarkRoundTrip(t *testing.T) {
+	t.Setenv(HomeEnv, t.TempDir())
+	source, target, other := t.TempDir(), t.TempDir(), t.TempDir()
+
+	mark, err := ReadWatermark(source, target)
+	if err != nil || !mark.IsZero() {
+		t.Fatalf("expected no watermark before the first run, got %v, %v", mark, err)
+	}
+
+	now := time.Date(2024, 6, 15, 14, 25, 1, 500, time.UTC)
+	err = WriteWatermark(source, target, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mark, err = ReadWatermark(source, target)
+	if err != nil || !mark.Equal(now) {
+		t.Errorf("expected %v, got %v, %v", now, mark, err)
+	}
+	mark, err = ReadWatermark(source, other)
+	if err != nil || !mark.IsZero() {
+		t.Errorf("expected each target to have its own watermark, got %v, %v", mark, err)
+	}
+}
+
+func TestFilterModifiedAfterWatermark(t *testing.T) {
+	source := t.TempDir()
+	mark := time.Now().Add(-time.Hour)
+	for name, modified := range map[string]time.Time{"old.mp3": mark.Add(-time.Hour), "new.mp3": mark.Add(time.Minute)} {
+		file := filepath.Join(source, name)
+		testutil.WriteMP3(t, file, nil)
+		err := os.Chtimes(file, modified, modified)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, skipped := GetFilteredMusicFiles(context.Background(), source, ScanOptions{}, Filter{ModifiedAfter: mark})
+	if got, want := relPaths(source, files), []string{"new.mp3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(skipped) != 1 || skipped[0].Reason != SkipNotModified {
+		t.Errorf("expected the old file skipped as not modified, got %v", skipped)
+	}
+}
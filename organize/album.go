@@ -0,0 +1,180 @@
+package organize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ErrAlbumRolledBack is the error given to the other tracks of an atomic album when one of its
+// tracks failed
+var ErrAlbumRolledBack = errors.New("album rolled back")
+
+// processAlbum places all the tracks of one album (a source folder) or none of them. The tracks
+// are copied first; if one fails, the copies already made are removed again and files an update
+// overwrote are put back. Only once every track is in place are sidecars carried, sources
+// removed in move mode and post hooks run. The results and errors line up with the files.
+func (o *Organizer) processAlbum(ctx context.Context, files []string, sourceFolder string, targetFolder string) ([]Result, []error) {
+	results := make([]Result, len(files))
+	errs := make([]error, len(files))
+
+	// Copy only to start with; everything that can't be undone waits until the album is in
+	copier := *o
+	copier.opts.Move = false
+	copier.opts.Sidecars = nil
+	copier.opts.PostHook = ""
+	copier.backups = make(map[string]string)
+
+	failed := -1
+	for i, file := range files {
+		results[i], errs[i] = copier.processFile(ctx, file, sourceFolder, targetFolder)
+		if errs[i] != nil {
+			failed = i
+			break
+		}
+	}
+
+	if failed >= 0 {
+		o.log.Warn("Album failed, rolling back", "folder", filepath.Dir(files[0]), "file", files[failed])
+		rolledBack := fmt.Errorf("%w: %s failed", ErrAlbumRolledBack, filepath.Base(files[failed]))
+
+		for i, file := range files {
+			switch {
+			case i == failed:
+				continue
+			case i > failed:
+				results[i] = Result{Source: file, Status: StatusFailed}
+				errs[i] = rolledBack
+			case results[i].Status == StatusCopied:
+				// Updated files are restored below; only files this run created go
+				if _, updated := copier.backups[results[i].Destination]; !updated {
+					o.log.Info("Removing copied track", "file", results[i].Destination)
+					err := os.Remove(results[i].Destination)
+					if err != nil {
+						o.log.Error("Error removing copied track", "file", results[i].Destination, "error", err)
+					}
+					o.removeEmptyFolders(o.targetFor(file, targetFolder), filepath.Dir(results[i].Destination))
+				}
+				results[i].Status = StatusFailed
+				errs[i] = rolledBack
+			}
+		}
+
+		for destination, backup := range copier.backups {
+			o.log.Info("Restoring updated track", "file", destination)
+			err := os.Rename(backup, destination)
+			if err != nil {
+				o.log.Error("Error restoring updated track", "file", destination, "backup", backup, "error", err)
+			}
+		}
+		return results, errs
+	}
+
+	// The album is in, so the files it replaced can go
+	for destination, backup := range copier.backups {
+		err := os.Remove(backup)
+		if err != nil {
+			o.log.Error("Error removing backup", "file", destination, "backup", backup, "error", err)
+		}
+	}
+
+	for i, file := range files {
+		result := &results[i]
+		placed := result.Status == StatusCopied || (result.Status == StatusSkipped && result.Reason == SkipAlreadyExists)
+		if !placed {
+			continue
+		}
+
+		o.copySidecars(ctx, file, result.Destination)
+
+		if o.opts.Move {
+			o.log.Debug("Deleting source file", "file", file)
+			err := o.removeSource(file)
+			if err != nil {
+				o.log.Error("Error deleting file", "file", file, "error", err)
+				result.Status = StatusFailed
+				errs[i] = err
+				continue
+			}
+			if result.Status == StatusCopied {
+				result.Status = StatusMoved
+			}
+		}
+
+		if o.opts.PostHook != "" && result.Status != StatusSkipped {
+			err := o.runHook(ctx, "post", o.opts.PostHook, file, result.Destination)
+			if err != nil {
+				o.log.Error("Error running post hook", "file", file, "error", err)
+			}
+		}
+	}
+	return results, errs
+}
+
+// backUp sets aside the file an update is about to overwrite, when placing an atomic album
+func (o *Organizer) backUp(destination string) error {
+	if o.backups == nil {
+		return nil
+	}
+	backup := filepath.Join(filepath.Dir(destination), ".muxic-backup-"+filepath.Base(destination))
+	err := os.Rename(destination, backup)
+	if err != nil {
+		return fmt.Errorf("error backing up %s: %v", destination, err)
+	}
+	o.backups[destination] = backup
+	return nil
+}
+
+// skipSmallAlbums groups the files into albums by their tags and leaves out those with fewer
+// than MinTracks tracks, returning the rest in their original order
+func (o *Organizer) skipSmallAlbums(files []string) ([]string, []musicutils.SkippedFile) {
+	type albumKey struct{ artist, album string }
+
+	keys := make([]albumKey, len(files))
+	counts := make(map[albumKey]int)
+	for i, file := range files {
+		info, _ := musicutils.ReadTrackInfo(file)
+		if slices.Contains(info.Missing, "album") {
+			// Not part of any album, so keyed on its own path
+			keys[i] = albumKey{album: file}
+		} else {
+			artist, album := info.AlbumKey()
+			keys[i] = albumKey{artist: artist, album: album}
+		}
+		counts[keys[i]]++
+	}
+
+	var kept []string
+	var skipped []musicutils.SkippedFile
+	for i, file := range files {
+		if counts[keys[i]] < o.opts.MinTracks {
+			skipped = append(skipped, musicutils.SkippedFile{Path: file, Reason: SkipTooFewTracks})
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, skipped
+}
+
+// removeEmptyFolders removes the folder and its parents, up to but not including the target
+// folder, for as long as they're empty
+func (o *Organizer) removeEmptyFolders(targetFolder string, folder string) {
+	for dir := folder; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, inside := musicutils.RelPathInside(targetFolder, dir); !inside || filepath.Clean(dir) == filepath.Clean(targetFolder) {
+			return
+		}
+		empty, err := musicutils.IsDirEmpty(dir)
+		if err != nil || !empty {
+			return
+		}
+		err = os.Remove(dir)
+		if err != nil {
+			o.log.Error("Error removing folder", "folder", dir, "error", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package organize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"muxic/internal/testutil"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/punkscience/movemusic"
+)
+
+// errCopyFailed is what copying a file set up by failCopying gives
+var errCopyFailed = errors.New("copy failed")
+
+// failCopying makes copying the named source file fail for the rest of the test
+func failCopying(t *testing.T, name string) {
+	t.Helper()
+	t.Cleanup(func() { copyMusic = movemusic.CopyMusic })
+	copyMusic = func(file string, targetFolder string, useFolders bool) (string, error) {
+		if filepath.Base(file) == name {
+			return "", errCopyFailed
+		}
+		return movemusic.CopyMusic(file, targetFolder, useFolders)
+	}
+}
+
+func TestAtomicAlbumRollsBackOnFailure(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "album", "1.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "album", "2.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	failCopying(t, "2.mp3")
+
+	organizer := New(Options{UseFolders: true, Move: true, AtomicAlbum: true, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", summary.Errors)
+	}
+	if !errors.Is(summary.Errors[0].Err, ErrAlbumRolledBack) || !errors.Is(summary.Errors[1].Err, errCopyFailed) {
+		t.Errorf("unexpected errors %v", summary.Errors)
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected the target to be left empty, found %v", files)
+	}
+	if entries, _ := os.ReadDir(target); len(entries) != 0 {
+		t.Errorf("expected empty folders to be removed, found %v", entries)
+	}
+	if files := testutil.ListFiles(t, source); len(files) != 2 {
+		t.Errorf("expected the sources to be kept, found %v", files)
+	}
+}
+
+func TestAtomicAlbumRestoresUpdatedFiles(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "album", "1.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "album", "2.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	failCopying(t, "2.mp3")
+
+	existing := filepath.Join(target, "Artist", "Album", "01 - One.mp3")
+	testutil.WriteFile(t, existing, []byte("the library's copy"))
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(existing, old, old)
+
+	organizer := New(Options{UseFolders: true, Update: true, AtomicAlbum: true, Logger: testutil.Logger(io.Discard)})
+	_, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatalf("expected the updated file to be restored: %v", err)
+	}
+	if string(data) != "the library's copy" {
+		t.Errorf("expected the previous file back, got %q", data)
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 1 {
+		t.Errorf("expected only the restored file, found %v", files)
+	}
+}
+
+func TestAtomicAlbumRemovesBackupsOnSuccess(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "album", "1.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "album", "2.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+
+	existing := filepath.Join(target, "Artist", "Album", "01 - One.mp3")
+	testutil.WriteFile(t, existing, []byte("old"))
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(existing, old, old)
+
+	organizer := New(Options{UseFolders: true, Update: true, AtomicAlbum: true, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	want := []string{"Artist/Album/01 - One.mp3", "Artist/Album/02 - Two.mp3"}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+		t.Errorf("expected %v, found %v", want, files)
+	}
+}
+
+func TestAtomicAlbumRollbackPrunesFormatTarget(t *testing.T) {
+	source, target, flacs := t.TempDir(), t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "album", "1.flac"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "album", "2.flac"), map[string]string{"TIT2": "Two"})
+
+	organizer := New(Options{
+		UseFolders:    true,
+		AtomicAlbum:   true,
+		RequiredTags:  []string{"artist"},
+		FormatTargets: map[string]string{"flac": flacs},
+		Logger:        testutil.Logger(io.Discard),
+	})
+	_, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := os.ReadDir(flacs); len(entries) != 0 {
+		t.Errorf("expected the format target to be left empty, found %v", entries)
+	}
+}
+
+func TestRemoveEmptyFoldersStaysInsideTarget(t *testing.T) {
+	music := t.TempDir()
+	lib, lib2 := filepath.Join(music, "lib"), filepath.Join(music, "lib2")
+	for _, dir := range []string{filepath.Join(lib, "Artist", "Album"), filepath.Join(lib2, "Artist", "Album")} {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	organizer := New(Options{Logger: testutil.Logger(io.Discard)})
+	organizer.removeEmptyFolders(lib, filepath.Join(lib2, "Artist", "Album"))
+	organizer.removeEmptyFolders(lib, filepath.Join(lib, "Artist", "Album"))
+
+	if !musicutils.FileExists(filepath.Join(lib2, "Artist", "Album")) {
+		t.Error("expected the sibling lib2 to be left alone")
+	}
+	if musicutils.FileExists(filepath.Join(lib, "Artist")) || !musicutils.FileExists(lib) {
+		t.Error("expected the empty folders inside lib removed, and lib itself kept")
+	}
+}
+
+func TestMinTracksSkipsSmallAlbums(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+
+	// A compilation counts as one album through its album artist
+	for i, artist := range []string{"One", "Two", "Three"} {
+		frames := testutil.Track(artist, "Hits", "Song "+artist, fmt.Sprint(i+1))
+		frames["TPE2"] = "Various"
+		testutil.WriteMP3(t, filepath.Join(source, "Hits", artist+".mp3"), frames)
+	}
+	testutil.WriteMP3(t, filepath.Join(source, "EP", "1.mp3"), testutil.Track("Band", "EP", "First", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "EP", "2.mp3"), testutil.Track("Band", "EP", "Second", "2"))
+	testutil.WriteMP3(t, filepath.Join(source, "single.mp3"), map[string]string{"TPE1": "Band", "TIT2": "Single"})
+
+	organizer := New(Options{UseFolders: true, MinTracks: 3, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	if summary.Total != 3 {
+		t.Errorf("expected only the compilation's 3 tracks processed, got %d", summary.Total)
+	}
+	var tooFew int
+	for _, skipped := range summary.Skipped {
+		if skipped.Reason == SkipTooFewTracks {
+			tooFew++
+		}
+	}
+	if tooFew != 3 {
+		t.Errorf("expected the EP and single reported as too few tracks, got %v", summary.Skipped)
+	}
+	for _, file := range testutil.ListFiles(t, target) {
+		if !strings.Contains(file, "Hits") {
+			t.Errorf("expected only the compilation in the target, found %s", file)
+		}
+	}
+}
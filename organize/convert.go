@@ -0,0 +1,110 @@
+package organize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"muxic/musicutils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/punkscience/movemusic"
+)
+
+// ErrNoFFmpeg is returned by NewFFmpeg when ffmpeg can't be found on the PATH
+var ErrNoFFmpeg = errors.New("ffmpeg not found on the PATH")
+
+// Transcoder converts music files to another format, carrying their tags across
+type Transcoder interface {
+	// Ext is the extension, with its dot, of the files the transcoder writes
+	Ext() string
+
+	// Transcode converts the source file into a new destination file
+	Transcode(ctx context.Context, source string, destination string) error
+}
+
+// ffmpegCodec is an output format ffmpeg can be asked for
+type ffmpegCodec struct {
+	encoder string
+	ext     string
+}
+
+// ffmpegCodecs are the formats NewFFmpeg accepts, by name
+var ffmpegCodecs = map[string]ffmpegCodec{
+	"mp3":  {encoder: "libmp3lame", ext: ".mp3"},
+	"aac":  {encoder: "aac", ext: ".m4a"},
+	"opus": {encoder: "libopus", ext: ".opus"},
+}
+
+// FFmpeg is a Transcoder that runs the ffmpeg command line tool
+type FFmpeg struct {
+	path    string
+	codec   ffmpegCodec
+	bitrate string
+}
+
+// NewFFmpeg returns a transcoder to the codec (mp3, aac or opus) at the bitrate, e.g. 192k. An
+// empty bitrate leaves it to ffmpeg.
+func NewFFmpeg(codec string, bitrate string) (*FFmpeg, error) {
+	c, found := ffmpegCodecs[strings.ToLower(codec)]
+	if !found {
+		return nil, fmt.Errorf("unsupported codec %q, expected mp3, aac or opus", codec)
+	}
+
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, ErrNoFFmpeg
+	}
+	return &FFmpeg{path: path, codec: c, bitrate: bitrate}, nil
+}
+
+// Ext returns the extension of the files the codec writes
+func (f *FFmpeg) Ext() string {
+	return f.codec.ext
+}
+
+// Transcode runs ffmpeg to convert the source, keeping only its audio and its tags
+func (f *FFmpeg) Transcode(ctx context.Context, source string, destination string) error {
+	args := []string{"-nostdin", "-loglevel", "error", "-n", "-i", source,
+		"-map", "0:a", "-map_metadata", "0", "-c:a", f.codec.encoder}
+	if f.bitrate != "" {
+		args = append(args, "-b:a", f.bitrate)
+	}
+	args = append(args, destination)
+
+	output, err := exec.CommandContext(ctx, f.path, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// converts reports whether the file is to be converted, which it isn't when there's no
+// Transcoder or the file is already in its format
+func (o *Organizer) converts(file string) bool {
+	return o.opts.Transcoder != nil && !strings.EqualFold(filepath.Ext(file), o.opts.Transcoder.Ext())
+}
+
+// transcode converts the source file straight into the destination, which has the
+// Transcoder's extension. A destination that's already there is left alone and
+// movemusic.ErrFileExists returned, without converting anything.
+func (o *Organizer) transcode(ctx context.Context, file string, destination string) (string, error) {
+	if musicutils.FileExists(destination) {
+		return destination, movemusic.ErrFileExists
+	}
+	err := os.MkdirAll(filepath.Dir(destination), 0755)
+	if err != nil {
+		return destination, err
+	}
+
+	o.log.Info("Converting file", "file", file, "destination", destination)
+	err = o.opts.Transcoder.Transcode(ctx, file, destination)
+	if err != nil {
+		// Don't leave a half written file to be mistaken for a finished one
+		os.Remove(destination)
+		return destination, err
+	}
+	return destination, nil
+}
@@ -0,0 +1,156 @@
+package organize
+
+import (
+	"context"
+	"errors"
+	"io"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeTranscoder "converts" a file by writing its name with a prefix, or fails when told to.
+// It counts the files it's asked to convert.
+type fakeTranscoder struct {
+	ext   string
+	fail  bool
+	calls int
+}
+
+func (f *fakeTranscoder) Ext() string {
+	return f.ext
+}
+
+func (f *fakeTranscoder) Transcode(ctx context.Context, source string, destination string) error {
+	f.calls++
+	if f.fail {
+		os.WriteFile(destination, []byte("half"), 0644)
+		return errors.New("transcoding failed")
+	}
+	return os.WriteFile(destination, []byte("converted "+filepath.Base(source)), 0644)
+}
+
+func TestTranscoderConvertsFromSource(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	transcoder := &fakeTranscoder{ext: ".opus"}
+	organizer := New(Options{UseFolders: true, Transcoder: transcoder, Logger: testutil.Logger(io.Discard)})
+	for run := 1; run <= 2; run++ {
+		summary, err := organizer.Organize(context.Background(), source, target)
+		if err != nil || len(summary.Errors) != 0 {
+			t.Fatalf("run %d: unexpected failure: %v %v", run, err, summary.Errors)
+		}
+		if run == 2 && (len(summary.Skipped) != 1 || summary.Skipped[0].Reason != SkipAlreadyExists) {
+			t.Errorf("expected the second run to skip the converted file, got %v", summary.Skipped)
+		}
+	}
+	if transcoder.calls != 1 {
+		t.Errorf("expected one conversion, got %d", transcoder.calls)
+	}
+
+	want := []string{"Artist/Album/01 - Song.opus"}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+		t.Errorf("expected %v, found %v", want, files)
+	}
+	data, _ := os.ReadFile(filepath.Join(target, "Artist", "Album", "01 - Song.opus"))
+	if string(data) != "converted song.mp3" {
+		t.Errorf("expected the source to be converted, got %q", data)
+	}
+}
+
+func TestFailedTranscodeLeavesNothing(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	organizer := New(Options{Move: true, UseFolders: true, Transcoder: &fakeTranscoder{ext: ".opus", fail: true}, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Errors) != 1 {
+		t.Errorf("expected the file to fail, got %v", summary.Errors)
+	}
+	for _, file := range testutil.ListFiles(t, target) {
+		t.Errorf("expected nothing left in the target, found %s", file)
+	}
+	if files := testutil.ListFiles(t, source); len(files) != 1 {
+		t.Errorf("expected the source kept, found %v", files)
+	}
+}
+
+func TestFFmpegArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the ffmpeg stub is a shell script")
+	}
+
+	// A stub ffmpeg that writes its arguments to the output file
+	bin := t.TempDir()
+	testutil.WriteFile(t, filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\nfor last; do :; done\necho \"$@\" > \"$last\"\n"))
+	os.Chmod(filepath.Join(bin, "ffmpeg"), 0755)
+	t.Setenv("PATH", bin)
+
+	_, err := NewFFmpeg("flac", "")
+	if err == nil {
+		t.Error("expected an unsupported codec to fail")
+	}
+
+	ffmpeg, err := NewFFmpeg("OPUS", "96k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ffmpeg.Ext() != ".opus" {
+		t.Errorf("expected .opus, got %s", ffmpeg.Ext())
+	}
+
+	destination := filepath.Join(t.TempDir(), "out.opus")
+	err = ffmpeg.Transcode(context.Background(), "in.flac", destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, _ := os.ReadFile(destination)
+	for _, want := range []string{"-i in.flac", "-map_metadata 0", "-c:a libopus", "-b:a 96k"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("expected %q in the ffmpeg arguments %q", want, args)
+		}
+	}
+}
+
+func TestNoFFmpeg(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := NewFFmpeg("mp3", "")
+	if !errors.Is(err, ErrNoFFmpeg) {
+		t.Errorf("expected ErrNoFFmpeg, got %v", err)
+	}
+}
+
+func TestTranscoderPlansConvertedName(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	organizer := New(Options{UseFolders: true, DryRun: true, Transcoder: &fakeTranscoder{ext: ".opus"}, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(target, "Artist", "Album", "01 - Song.opus")
+	if len(summary.Results) != 1 || summary.Results[0].Destination != want {
+		t.Errorf("expected the dry run to plan %s, got %v", want, summary.Results)
+	}
+
+	// A file already in the format is copied as it is
+	transcoder := &fakeTranscoder{ext: ".mp3"}
+	organizer = New(Options{UseFolders: true, Transcoder: transcoder, Logger: testutil.Logger(io.Discard)})
+	_, err = organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcoder.calls != 0 || !slices.Equal(testutil.ListFiles(t, target), []string{"Artist/Album/01 - Song.mp3"}) {
+		t.Errorf("expected a plain copy, got %d conversions and %v", transcoder.calls, testutil.ListFiles(t, target))
+	}
+}
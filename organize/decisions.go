@@ -0,0 +1,86 @@
+package organize
+
+import (
+	"encoding/json"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+)
+
+// decisionLog remembers the destination chosen for each source file, so later runs put the file
+// in the same place even if the naming rules have changed since
+type decisionLog struct {
+	file         string
+	Destinations map[string]string `json:"destinations"`
+}
+
+// loadDecisionLog reads the decision log from the file. A missing file gives an empty log.
+func loadDecisionLog(file string) (*decisionLog, error) {
+	decisions := &decisionLog{file: file, Destinations: make(map[string]string)}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return decisions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, decisions)
+	if err != nil {
+		return nil, err
+	}
+	if decisions.Destinations == nil {
+		decisions.Destinations = make(map[string]string)
+	}
+	return decisions, nil
+}
+
+// lookup returns the destination recorded for the source file, as long as it lies inside the
+// target folder
+func (d *decisionLog) lookup(file string, targetFolder string) (string, bool) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+	destination, found := d.Destinations[absFile]
+	if !found {
+		return "", false
+	}
+
+	absTarget, err := filepath.Abs(targetFolder)
+	if err != nil {
+		return "", false
+	}
+	if _, inside := musicutils.RelPathInside(absTarget, destination); !inside {
+		return "", false
+	}
+	return destination, true
+}
+
+// record remembers the destination chosen for the source file
+func (d *decisionLog) record(file string, destination string) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return
+	}
+	absDestination, err := filepath.Abs(destination)
+	if err != nil {
+		return
+	}
+	d.Destinations[absFile] = absDestination
+}
+
+// save writes the decision log back to its file
+func (d *decisionLog) save() error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(d.file), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.file, append(data, '\n'), 0644)
+}
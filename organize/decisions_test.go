@@ -0,0 +1,51 @@
+package organize
+
+import (
+	"context"
+	"io"
+	"muxic/internal/testutil"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDecisionLogKeepsDestination(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	decisions := filepath.Join(t.TempDir(), "decisions.json")
+	song := filepath.Join(source, "song.mp3")
+	testutil.WriteMP3(t, song, testutil.Track("Artist", "Album", "Song", "1"))
+
+	organizer := New(Options{UseFolders: true, DecisionLog: decisions, Logger: testutil.Logger(io.Discard)})
+	_, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new title would name the file differently, as a change to the naming rules would
+	testutil.WriteMP3(t, song, testutil.Track("Artist", "Album", "Renamed", "1"))
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+	if files, want := testutil.ListFiles(t, target), []string{"Artist/Album/01 - Song.mp3"}; !slices.Equal(files, want) {
+		t.Errorf("expected the remembered destination to be kept, found %v", files)
+	}
+
+	organizer = New(Options{UseFolders: true, DecisionLog: decisions, Recompute: true, Logger: testutil.Logger(io.Discard)})
+	_, err = organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, want := testutil.ListFiles(t, target), []string{"Artist/Album/01 - Renamed.mp3", "Artist/Album/01 - Song.mp3"}; !slices.Equal(files, want) {
+		t.Errorf("expected Recompute to work the destination out afresh, found %v", files)
+	}
+
+	log, err := loadDecisionLog(decisions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	absSong, _ := filepath.Abs(song)
+	if filepath.Base(log.Destinations[absSong]) != "01 - Renamed.mp3" {
+		t.Errorf("expected the recomputed destination to be remembered, got %v", log.Destinations)
+	}
+}
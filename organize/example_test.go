@@ -0,0 +1,43 @@
+package organize_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"muxic/organize"
+	"os"
+	"path/filepath"
+)
+
+func ExampleOrganizer_Organize() {
+	source, _ := os.MkdirTemp("", "downloads")
+	defer os.RemoveAll(source)
+	library, _ := os.MkdirTemp("", "library")
+	defer os.RemoveAll(library)
+
+	// An untagged track takes its title from the file name
+	os.WriteFile(filepath.Join(source, "song.mp3"), []byte{0xff, 0xfb, 0x90, 0x00}, 0644)
+
+	organizer := organize.New(organize.Options{
+		UseFolders: true,
+		Move:       true,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	summary, err := organizer.Organize(context.Background(), source, library)
+	if err != nil {
+		// The run was cancelled or couldn't start
+		fmt.Println(err)
+		return
+	}
+	for _, fe := range summary.Errors {
+		fmt.Println(fe.Path, fe.Err)
+	}
+	fmt.Printf("Completed %d of %d files\n", summary.Completed, summary.Total)
+
+	_, err = os.Stat(filepath.Join(library, "Unknown", "Unknown", "01 - Song.mp3"))
+	fmt.Println(err == nil)
+	// Output:
+	// Completed 1 of 1 files
+	// true
+}
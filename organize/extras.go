@@ -0,0 +1,204 @@
+package organize
+
+import (
+	"context"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// copyExtras copies (or moves) the non-music files (cover art, booklets, .nfo files and the
+// like) from each source folder that had tracks placed into the destination album folder those
+// tracks went to. Each album folder ends up with a single cover image, the largest on offer.
+func (o *Organizer) copyExtras(ctx context.Context, albumFolders map[string]string) {
+	// Destination album folder -> candidate cover images from the source folders
+	covers := make(map[string][]string)
+
+	// Work through the folders in a stable order
+	sourceDirs := make([]string, 0, len(albumFolders))
+	for sourceDir := range albumFolders {
+		sourceDirs = append(sourceDirs, sourceDir)
+	}
+	sort.Strings(sourceDirs)
+
+	for _, sourceDir := range sourceDirs {
+		destDir := albumFolders[sourceDir]
+
+		for _, extra := range o.findExtras(sourceDir) {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Several source folders (e.g. one per disc) can feed the same album, so covers
+			// are chosen between once all of them are known
+			if isCover(filepath.Base(extra)) {
+				covers[destDir] = append(covers[destDir], extra)
+				continue
+			}
+
+			target := filepath.Join(destDir, filepath.Base(extra))
+			if o.opts.DryRun {
+				o.log.Info("Would carry extra file", "file", extra, "destination", target)
+				continue
+			}
+
+			if musicutils.FileExists(target) {
+				o.log.Debug("Extra file already exists, skipping", "file", target)
+			} else {
+				o.log.Info("Copying extra file", "file", extra, "destination", target)
+				err := musicutils.CopyFile(ctx, extra, target)
+				if err != nil {
+					o.log.Error("Error copying extra file", "file", extra, "error", err)
+					continue
+				}
+				o.applyFileMode(target)
+			}
+
+			if o.opts.Move {
+				err := o.removeSource(extra)
+				if err != nil {
+					o.log.Error("Error deleting extra file", "file", extra, "error", err)
+				}
+			}
+		}
+	}
+
+	o.placeCovers(ctx, covers)
+}
+
+// placeCovers leaves each destination album folder with one cover image, the one with the most
+// pixels out of the candidates and any cover already there. Other covers in the folder are
+// removed, and in move mode the source covers are too.
+func (o *Organizer) placeCovers(ctx context.Context, covers map[string][]string) {
+	destDirs := make([]string, 0, len(covers))
+	for destDir := range covers {
+		destDirs = append(destDirs, destDir)
+	}
+	sort.Strings(destDirs)
+
+	for _, destDir := range destDirs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		var existing []string
+		entries, _ := os.ReadDir(destDir)
+		for _, entry := range entries {
+			if !entry.IsDir() && isCover(entry.Name()) {
+				existing = append(existing, filepath.Join(destDir, entry.Name()))
+			}
+		}
+
+		best := ""
+		bestPixels := -1
+		for _, cover := range append(existing, covers[destDir]...) {
+			if pixels := imagePixels(cover); pixels > bestPixels {
+				best = cover
+				bestPixels = pixels
+			}
+		}
+
+		target := filepath.Join(destDir, filepath.Base(best))
+		if o.opts.DryRun {
+			if best != target {
+				o.log.Info("Would carry cover", "file", best, "destination", target)
+			}
+			continue
+		}
+
+		if best != target {
+			o.log.Info("Copying cover", "file", best, "destination", target)
+			err := musicutils.CopyFile(ctx, best, target)
+			if err != nil {
+				o.log.Error("Error copying cover", "file", best, "error", err)
+				continue
+			}
+			o.applyFileMode(target)
+		}
+
+		targetInfo, _ := os.Stat(target)
+		for _, cover := range existing {
+			// On a case-insensitive filesystem cover.jpg and Cover.jpg are the same file
+			coverInfo, err := os.Stat(cover)
+			if cover == target || (err == nil && targetInfo != nil && os.SameFile(coverInfo, targetInfo)) {
+				continue
+			}
+			o.log.Info("Removing redundant cover", "file", cover)
+			err = os.Remove(cover)
+			if err != nil {
+				o.log.Error("Error removing cover", "file", cover, "error", err)
+			}
+		}
+
+		if o.opts.Move {
+			for _, cover := range covers[destDir] {
+				err := o.removeSource(cover)
+				if err != nil {
+					o.log.Error("Error deleting extra file", "file", cover, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// isCover checks to see if the file name is a cover image, e.g. cover.jpg or Cover.png
+func isCover(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".gif" {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), "cover")
+}
+
+// imagePixels returns the width times height of the image, or 0 if it can't be decoded
+func imagePixels(file string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0
+	}
+	return config.Width * config.Height
+}
+
+// findExtras returns the non-music files directly inside the folder, leaving out hidden files
+// and anything handled as a sidecar
+func (o *Organizer) findExtras(folder string) []string {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		o.log.Error("Error reading folder", "folder", folder, "error", err)
+		return nil
+	}
+
+	var extras []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || musicutils.IsMusicFile(name) {
+			continue
+		}
+
+		isSidecar := false
+		for _, ext := range o.opts.Sidecars {
+			if strings.EqualFold(filepath.Ext(name), "."+strings.TrimPrefix(ext, ".")) {
+				isSidecar = true
+				break
+			}
+		}
+		if isSidecar {
+			continue
+		}
+
+		extras = append(extras, filepath.Join(folder, name))
+	}
+	return extras
+}
@@ -0,0 +1,112 @@
+package organize
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"muxic/internal/testutil"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtrasFollowTheAlbum(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	album := filepath.Join(source, "rips", "album")
+	testutil.WriteMP3(t, filepath.Join(album, "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(album, "02.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	testutil.WriteFile(t, filepath.Join(album, "cover.jpg"), []byte("jpeg"))
+	testutil.WriteFile(t, filepath.Join(album, "booklet.pdf"), []byte("pdf"))
+	testutil.WriteFile(t, filepath.Join(album, ".DS_Store"), []byte("hidden"))
+	testutil.WriteFile(t, filepath.Join(album, "01.cue"), []byte("sidecar"))
+
+	organizer := New(Options{UseFolders: true, IncludeNonMusic: true, Sidecars: []string{"cue"}, Logger: testutil.Logger(io.Discard)})
+	if _, err := organizer.Organize(context.Background(), source, target); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Artist/Album/01 - One.cue", "Artist/Album/01 - One.mp3", "Artist/Album/02 - Two.mp3", "Artist/Album/booklet.pdf", "Artist/Album/cover.jpg"}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+		t.Errorf("target holds %v, want %v", files, want)
+	}
+}
+
+func TestExtrasDryRun(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "album", "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteFile(t, filepath.Join(source, "album", "booklet.pdf"), []byte("pdf"))
+	testutil.WriteFile(t, filepath.Join(source, "album", "cover.jpg"), []byte("jpeg"))
+
+	var out bytes.Buffer
+	organizer := New(Options{UseFolders: true, DryRun: true, IncludeNonMusic: true, Logger: testutil.Logger(&out)})
+	if _, err := organizer.Organize(context.Background(), source, target); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`msg="Would carry extra file" file=` + filepath.Join(source, "album", "booklet.pdf") + " destination=" + filepath.Join(target, "Artist", "Album", "booklet.pdf"),
+		`msg="Would carry cover" file=` + filepath.Join(source, "album", "cover.jpg") + " destination=" + filepath.Join(target, "Artist", "Album", "cover.jpg"),
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %s, got:\n%s", want, out.String())
+		}
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected nothing in the target, found %v", files)
+	}
+}
+
+func TestExtrasKeepLargestCover(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "CD1", "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "CD2", "01.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	testutil.WriteFile(t, filepath.Join(source, "CD1", "cover.png"), testutil.PNG(10, 10))
+	testutil.WriteFile(t, filepath.Join(source, "CD2", "Cover.png"), testutil.PNG(50, 50))
+
+	organizer := New(Options{UseFolders: true, IncludeNonMusic: true, Logger: testutil.Logger(io.Discard)})
+	if _, err := organizer.Organize(context.Background(), source, target); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Artist/Album/01 - One.mp3", "Artist/Album/02 - Two.mp3", "Artist/Album/Cover.png"}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+		t.Errorf("target holds %v, want %v", files, want)
+	}
+}
+
+func TestCoverReplacesSmallerExistingCover(t *testing.T) {
+	for _, existing := range []int{10, 80} {
+		source, target := t.TempDir(), t.TempDir()
+		testutil.WriteMP3(t, filepath.Join(source, "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+		testutil.WriteFile(t, filepath.Join(source, "cover.png"), testutil.PNG(40, 40))
+		testutil.WriteFile(t, filepath.Join(target, "Artist", "Album", "cover.jpg"), testutil.PNG(existing, existing))
+
+		organizer := New(Options{UseFolders: true, IncludeNonMusic: true, Logger: testutil.Logger(io.Discard)})
+		_, err := organizer.Organize(context.Background(), source, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"Artist/Album/01 - One.mp3", "Artist/Album/cover.png"}
+		if existing > 40 {
+			want = []string{"Artist/Album/01 - One.mp3", "Artist/Album/cover.jpg"}
+		}
+		if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+			t.Errorf("existing %dpx cover: expected %v, found %v", existing, want, files)
+		}
+	}
+}
+
+func TestImagePixels(t *testing.T) {
+	folder := t.TempDir()
+	testutil.WriteFile(t, filepath.Join(folder, "cover.png"), testutil.PNG(30, 20))
+	testutil.WriteFile(t, filepath.Join(folder, "broken.jpg"), []byte("not an image"))
+
+	if pixels := imagePixels(filepath.Join(folder, "cover.png")); pixels != 600 {
+		t.Errorf("expected 600 pixels, got %d", pixels)
+	}
+	if pixels := imagePixels(filepath.Join(folder, "broken.jpg")); pixels != 0 {
+		t.Errorf("expected 0 pixels for a broken image, got %d", pixels)
+	}
+}
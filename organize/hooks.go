@@ -0,0 +1,28 @@
+package organize
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runHook runs a hook command with the paths appended as arguments, logging whatever it prints.
+// The command is split on spaces; it isn't run through a shell.
+func (o *Organizer) runHook(ctx context.Context, name string, command string, paths ...string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	o.log.Debug("Running hook", "hook", name, "command", command, "args", paths)
+	cmd := exec.CommandContext(ctx, fields[0], append(fields[1:], paths...)...)
+	output, err := cmd.CombinedOutput()
+	if len(output) > 0 {
+		o.log.Info("Hook output", "hook", name, "output", strings.TrimSpace(string(output)))
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package organize
+
+import (
+	"context"
+	"io"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHooksGetThePaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a Unix shell")
+	}
+	source, target, scripts := t.TempDir(), t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	log := filepath.Join(scripts, "hooks.log")
+	hook := filepath.Join(scripts, "hook.sh")
+	testutil.WriteFile(t, hook, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\n"))
+	os.Chmod(hook, 0755)
+
+	organizer := New(Options{UseFolders: true, PreHook: hook + " pre", PostHook: hook + " post", Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(source, "song.mp3")
+	want := "pre " + src + "\npost " + src + " " + filepath.Join(target, "Artist", "Album", "01 - Song.mp3") + "\n"
+	if string(data) != want {
+		t.Errorf("expected the hooks to log\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestFailingPreHookLeavesTheFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a Unix shell")
+	}
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	organizer := New(Options{Move: true, UseFolders: true, PreHook: "false", Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0].Err.Error(), "pre hook failed") {
+		t.Errorf("expected the pre hook failure, got %v", summary.Errors)
+	}
+	if files := testutil.ListFiles(t, source); len(files) != 1 {
+		t.Errorf("expected the source to be left alone, found %v", files)
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected nothing in the target, found %v", files)
+	}
+}
@@ -0,0 +1,47 @@
+package organize
+
+import (
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+)
+
+// applyFileMode sets the configured permissions on a file placed in the target
+func (o *Organizer) applyFileMode(file string) {
+	if o.opts.FileMode == 0 {
+		return
+	}
+	err := os.Chmod(file, o.opts.FileMode)
+	if err != nil {
+		o.log.Error("Error setting file permissions", "file", file, "error", err)
+	}
+}
+
+// applyDirMode sets the configured permissions on folders created in the target
+func (o *Organizer) applyDirMode(folders []string) {
+	if o.opts.DirMode == 0 {
+		return
+	}
+	for _, folder := range folders {
+		err := os.Chmod(folder, o.opts.DirMode)
+		if err != nil {
+			o.log.Error("Error setting folder permissions", "folder", folder, "error", err)
+		}
+	}
+}
+
+// missingFolders returns the folders from the given folder up to, but not including, the target
+// folder that don't exist yet: the ones placing a file in the folder will create
+func missingFolders(targetFolder string, folder string) []string {
+	var missing []string
+	for dir := folder; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, inside := musicutils.RelPathInside(targetFolder, dir); !inside || filepath.Clean(dir) == filepath.Clean(targetFolder) {
+			break
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	return missing
+}
@@ -0,0 +1,55 @@
+package organize
+
+import (
+	"context"
+	"io"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileAndDirModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions don't apply on Windows")
+	}
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "other.mp3"), testutil.Track("Other", "Album", "Other", "1"))
+
+	// The library already has a folder for the first artist, with its own permissions
+	existing := filepath.Join(target, "Artist")
+	err := os.Mkdir(existing, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	organizer := New(Options{UseFolders: true, FileMode: 0600, DirMode: 0750, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	modes := map[string]os.FileMode{
+		filepath.Join(target, "Artist", "Album", "01 - Song.mp3"): 0600,
+		filepath.Join(target, "Artist", "Album"):                  0750,
+		filepath.Join(target, "Other", "Album"):                   0750,
+		filepath.Join(target, "Other"):                            0750,
+		existing:                                                  0700,
+	}
+	for path, want := range modes {
+		stat, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != want {
+			t.Errorf("expected %s to have mode %v, got %v", path, want, stat.Mode().Perm())
+		}
+	}
+
+	stat, err := os.Stat(target)
+	if err != nil || stat.Mode().Perm() == 0750 {
+		t.Errorf("expected the target folder itself to be left alone")
+	}
+}
@@ -0,0 +1,808 @@
+// Package organize copies or moves a library of music files into a tidy artist/album folder
+// layout. It is the logic behind the muxic copy and watch commands, exposed so other Go programs
+// can use it without shelling out. See the Organizer.Organize example for a whole run.
+package organize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/punkscience/movemusic"
+)
+
+// Options controls how an Organizer handles each file
+type Options struct {
+	// UseFolders builds an artist/album folder tree instead of flat file names
+	UseFolders bool
+
+	// KeepStructure mirrors each file's path relative to the source folder under the target,
+	// with every part sanitized, instead of building folders from the tags
+	KeepStructure bool
+
+	// BucketByLetter files each artist folder under a folder named after the artist's first
+	// letter, or # when it doesn't start with a letter
+	BucketByLetter bool
+
+	// Move deletes each source file once it is safely in the target
+	Move bool
+
+	// AllowCopyFallback copies instead of failing when Move is set but the source folder is
+	// read-only
+	AllowCopyFallback bool
+
+	// Update replaces a file that already exists in the target when the source file has been
+	// modified more recently
+	Update bool
+
+	// IgnoreSpace skips the check that the target volume has room for the files
+	IgnoreSpace bool
+
+	// FileMode, when non-zero, is the permissions given to files placed in the target
+	FileMode os.FileMode
+
+	// DirMode, when non-zero, is the permissions given to folders a run creates in the target.
+	// Folders that were already there are left alone.
+	DirMode os.FileMode
+
+	// Trash sends deleted source files to the trash instead of removing them for good
+	Trash bool
+
+	// DryRun only reports what would be done
+	DryRun bool
+
+	// FailFast stops at the first file that fails instead of carrying on
+	FailFast bool
+
+	// Exclude holds glob patterns for files and folders to leave out of the scan, on top of
+	// the source's .muxicignore file
+	Exclude []string
+
+	// IncludeHidden processes files and folders whose names start with a dot, which are
+	// otherwise skipped
+	IncludeHidden bool
+
+	// Filter chooses which of the scanned files are processed
+	Filter musicutils.Filter
+
+	// Sidecars lists extensions of same-named files (e.g. cue, log, lrc) that travel with each track
+	Sidecars []string
+
+	// IncludeNonMusic carries cover art, booklets and other extras into each album folder
+	IncludeNonMusic bool
+
+	// QuarantineFolder, when set, receives a copy of every file that fails processing
+	QuarantineFolder string
+
+	// PreHook is a command run before each file is processed, with the source path as its last
+	// argument. If it fails the file is counted as failed and left alone. Like PostHook it is
+	// split on whitespace without a shell, so neither the program's path nor its own arguments
+	// can contain spaces; wrap anything more in a script.
+	PreHook string
+
+	// PostHook is a command run after each file is copied or moved, with the source and
+	// destination paths as its last two arguments. A failure is logged but doesn't fail the file.
+	PostHook string
+
+	// DecisionLog, when set, is a JSON file remembering the destination chosen for each source
+	// file. Later runs reuse a remembered destination instead of working it out again.
+	DecisionLog string
+
+	// Recompute works every destination out afresh, replacing what the decision log remembers
+	Recompute bool
+
+	// MaxFiles, when non-zero, caps how many files a run will process. A bigger scan stops the
+	// run with ErrTooManyFiles unless ConfirmTooMany says to carry on.
+	MaxFiles int
+
+	// ConfirmTooMany, when set, is asked whether to carry on when the scan finds more than
+	// MaxFiles files
+	ConfirmTooMany func(total int, max int) bool
+
+	// FormatTargets routes files to their own target folder by extension, e.g. "flac" to a
+	// lossless library. Extensions are lower case and without the dot; unlisted ones go to the
+	// target folder given to Organize.
+	FormatTargets map[string]string
+
+	// Transcoder, when set, converts each file straight from the source into its format
+	// instead of copying it. Files already in that format are copied as usual. Update has no
+	// effect on converted files.
+	Transcoder Transcoder
+
+	// RequiredTags, when set, names the tags ("artist", "album" and "title") a file must have.
+	// A file missing any of them fails with ErrMissingTags rather than being filed under
+	// Unknown.
+	RequiredTags []string
+
+	// MinTracks, when non-zero, only processes albums (grouped by album artist and album) with
+	// at least this many tracks among the files found. Files without an album tag count as
+	// albums of one.
+	MinTracks int
+
+	// AtomicAlbum treats each source folder as an album that is placed as a whole: if any of
+	// its tracks fails, the tracks already copied are removed again and, in move mode, none of
+	// its sources are deleted
+	AtomicAlbum bool
+
+	// Progress, when set, is called after each file with how far through the run's bytes it is
+	Progress func(progress Progress)
+
+	// Confirm, when set, is asked before any file is processed, with the number of files found.
+	// Returning false stops the run with ErrAborted. It isn't asked in a dry run.
+	Confirm func(total int) bool
+
+	// Logger receives progress and error messages; nil uses slog.Default()
+	Logger *slog.Logger
+}
+
+// ErrAborted is returned by Organize when the Confirm option turns the run down
+var ErrAborted = errors.New("aborted")
+
+// ErrMissingTags is the error for a file that lacks one of the RequiredTags
+var ErrMissingTags = errors.New("missing tags")
+
+// ErrTooManyFiles is returned by Organize when the scan finds more files than the MaxFiles cap
+var ErrTooManyFiles = errors.New("too many files")
+
+// ErrReadOnlySource is returned by Organize when moving from a source folder that can't be
+// written to, unless AllowCopyFallback is set
+var ErrReadOnlySource = errors.New("the source folder is read-only, so files can't be moved out of it")
+
+// FileError records a file that failed to process and why
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Summary describes the outcome of an Organize run
+type Summary struct {
+	// Total is the number of music files found that passed the filter
+	Total int
+
+	// Completed is the number of those files that were processed, successfully or not
+	Completed int
+
+	// BytesWritten is the size of the music files written to the target
+	BytesWritten int64
+
+	// BytesRemoved is the size of the source music files removed (or trashed) after moving
+	BytesRemoved int64
+
+	// Errors lists the files that failed
+	Errors []FileError
+
+	// Skipped lists the files that were left out by the filter or skipped while processing,
+	// with the reason for each
+	Skipped []musicutils.SkippedFile
+
+	// Results holds what happened to each processed file, in processing order
+	Results []Result
+}
+
+// Organizer copies or moves music files into a target library
+type Organizer struct {
+	opts Options
+	log  *slog.Logger
+
+	// decisions is the loaded decision log during an Organize run, if one is in use
+	decisions *decisionLog
+
+	// backups, while placing an atomic album, maps each destination an update overwrote to a
+	// backup of the file that was there, so a rollback can put it back
+	backups map[string]string
+}
+
+// New returns an Organizer using the options
+func New(opts Options) *Organizer {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Organizer{opts: opts, log: logger}
+}
+
+// ScanOptions returns the scan options Organize uses for a run from source to target: the
+// Exclude patterns, with the target, format target and quarantine folders left out when they
+// live inside the source so the files being organized aren't picked up again. The watch command
+// builds its scan the same way.
+func (o *Organizer) ScanOptions(source string, target string) (musicutils.ScanOptions, error) {
+	scan := musicutils.ScanOptions{Exclude: o.opts.Exclude, IncludeHidden: o.opts.IncludeHidden}
+
+	nested, err := musicutils.IsSubPath(source, target)
+	if err != nil {
+		return scan, fmt.Errorf("error checking source and target folders: %v", err)
+	}
+	if nested {
+		absTarget, _ := filepath.Abs(target)
+		o.log.Info("Target folder is inside the source folder, it will be excluded from scanning", "target", target)
+		scan.SkipDirs = append(scan.SkipDirs, absTarget)
+	}
+
+	for _, formatTarget := range o.opts.FormatTargets {
+		nested, err := musicutils.IsSubPath(source, formatTarget)
+		if err != nil {
+			return scan, fmt.Errorf("error checking source and target folders: %v", err)
+		}
+		if nested {
+			absTarget, _ := filepath.Abs(formatTarget)
+			scan.SkipDirs = append(scan.SkipDirs, absTarget)
+		}
+	}
+
+	if o.opts.QuarantineFolder != "" {
+		nested, err := musicutils.IsSubPath(source, o.opts.QuarantineFolder)
+		if err != nil {
+			return scan, fmt.Errorf("error checking source and quarantine folders: %v", err)
+		}
+		if nested {
+			absQuarantine, _ := filepath.Abs(o.opts.QuarantineFolder)
+			scan.SkipDirs = append(scan.SkipDirs, absQuarantine)
+		}
+	}
+
+	return scan, nil
+}
+
+// Organize scans the source folder and processes every matching music file into the target
+// folder. Failures on individual files are collected in the summary rather than returned; the
+// error is only set when the run couldn't start or the context was cancelled. The source may
+// also be a single music file.
+func (o *Organizer) Organize(ctx context.Context, source string, target string) (Summary, error) {
+	var summary Summary
+
+	// A single file source is processed on its own, with its folder as the library root
+	sourceFolder := source
+	if stat, err := os.Stat(source); err == nil && !stat.IsDir() {
+		sourceFolder = filepath.Dir(source)
+	}
+
+	// Find out now if the sources can't be removed, rather than after each copy
+	if o.opts.Move && !o.opts.DryRun && !writable(sourceFolder) {
+		if !o.opts.AllowCopyFallback {
+			return summary, ErrReadOnlySource
+		}
+		o.log.Warn("Source folder is read-only, files will be copied but not removed", "folder", sourceFolder)
+
+		fallback := o.opts
+		fallback.Move = false
+		fallback.Confirm = nil
+		o = &Organizer{opts: fallback, log: o.log}
+	}
+
+	scan, err := o.ScanOptions(source, target)
+	if err != nil {
+		return summary, err
+	}
+
+	allFiles, skipped := musicutils.GetFilteredMusicFiles(ctx, source, scan, o.opts.Filter)
+	if o.opts.MinTracks > 0 {
+		var small []musicutils.SkippedFile
+		allFiles, small = o.skipSmallAlbums(allFiles)
+		skipped = append(skipped, small...)
+	}
+	summary.Total = len(allFiles)
+	summary.Skipped = skipped
+
+	// Guard against a mistyped source sweeping up a whole disk
+	if o.opts.MaxFiles > 0 && len(allFiles) > o.opts.MaxFiles && ctx.Err() == nil {
+		if o.opts.ConfirmTooMany == nil || !o.opts.ConfirmTooMany(len(allFiles), o.opts.MaxFiles) {
+			return summary, fmt.Errorf("%w: found %d, the limit is %d", ErrTooManyFiles, len(allFiles), o.opts.MaxFiles)
+		}
+	}
+
+	if !o.opts.DryRun && !o.opts.IgnoreSpace && len(allFiles) > 0 && ctx.Err() == nil {
+		err := o.checkSpace(allFiles, sourceFolder, target)
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	if o.opts.Confirm != nil && !o.opts.DryRun && len(allFiles) > 0 && ctx.Err() == nil {
+		if !o.opts.Confirm(len(allFiles)) {
+			return summary, ErrAborted
+		}
+	}
+
+	if o.opts.DecisionLog != "" && !o.opts.DryRun {
+		decisions, err := loadDecisionLog(o.opts.DecisionLog)
+		if err != nil {
+			return summary, fmt.Errorf("error reading decision log: %v", err)
+		}
+		o.decisions = decisions
+		defer func() {
+			err := decisions.save()
+			if err != nil {
+				o.log.Error("Error saving decision log", "file", o.opts.DecisionLog, "error", err)
+			}
+			o.decisions = nil
+		}()
+	}
+
+	// Source folder -> destination album folder, for carrying the non-music extras along
+	albumFolders := make(map[string]string)
+
+	// Files go one at a time, or a whole source folder at a time for atomic albums
+	batches := make([][]string, 0, len(allFiles))
+	for _, file := range allFiles {
+		last := len(batches) - 1
+		if o.opts.AtomicAlbum && last >= 0 && filepath.Dir(batches[last][0]) == filepath.Dir(file) {
+			batches[last] = append(batches[last], file)
+		} else {
+			batches = append(batches, []string{file})
+		}
+	}
+
+	var progress *progressTracker
+	if o.opts.Progress != nil {
+		var totalBytes int64
+		for _, file := range allFiles {
+			if stat, err := os.Stat(file); err == nil {
+				totalBytes += stat.Size()
+			}
+		}
+		progress = newProgressTracker(len(allFiles), totalBytes, time.Now)
+	}
+
+	stop := false
+	for _, batch := range batches {
+		if ctx.Err() != nil || stop {
+			break
+		}
+
+		var results []Result
+		var errs []error
+		if o.opts.AtomicAlbum {
+			results, errs = o.processAlbum(ctx, batch, sourceFolder, target)
+		} else {
+			result, err := o.processFile(ctx, batch[0], sourceFolder, target)
+			results, errs = []Result{result}, []error{err}
+		}
+
+		for i, file := range batch {
+			result, err := results[i], errs[i]
+			summary.Completed++
+			summary.Results = append(summary.Results, result)
+
+			if progress != nil {
+				o.opts.Progress(progress.add(result.Bytes))
+			}
+
+			// What's written can differ from the source, e.g. when converting
+			if result.Status == StatusCopied || result.Status == StatusMoved {
+				if stat, err := os.Stat(result.Destination); err == nil {
+					summary.BytesWritten += stat.Size()
+				}
+			}
+
+			// In move mode the source of an existing file is removed too
+			if result.Status == StatusMoved || (o.opts.Move && err == nil && result.Reason == SkipAlreadyExists) {
+				summary.BytesRemoved += result.Bytes
+			}
+
+			if result.Status == StatusSkipped {
+				summary.Skipped = append(summary.Skipped, musicutils.SkippedFile{Path: file, Reason: result.Reason})
+			}
+
+			// The first track placed from a folder decides where its extras go
+			if o.opts.IncludeNonMusic && err == nil && result.Destination != "" {
+				sourceDir := filepath.Dir(file)
+				if _, found := albumFolders[sourceDir]; !found {
+					albumFolders[sourceDir] = filepath.Dir(result.Destination)
+				}
+			}
+
+			if err != nil {
+				summary.Errors = append(summary.Errors, FileError{Path: file, Err: err})
+
+				// Tracks only caught up in an album's rollback are fine where they are
+				if o.opts.QuarantineFolder != "" && ctx.Err() == nil && !errors.Is(err, ErrAlbumRolledBack) {
+					if o.opts.DryRun {
+						o.log.Info("Would quarantine file", "file", file)
+					} else {
+						qerr := o.quarantineFile(ctx, file, sourceFolder, err)
+						if qerr != nil {
+							o.log.Error("Error quarantining file", "file", file, "error", qerr)
+						}
+					}
+				}
+
+				if o.opts.FailFast {
+					stop = true
+				}
+			}
+		}
+	}
+
+	if o.opts.IncludeNonMusic && ctx.Err() == nil {
+		o.copyExtras(ctx, albumFolders)
+	}
+
+	return summary, ctx.Err()
+}
+
+// checkRequiredTags returns ErrMissingTags, naming the tags, if the file lacks any of the
+// RequiredTags
+func (o *Organizer) checkRequiredTags(file string) error {
+	info, _ := musicutils.ReadTrackInfo(file)
+
+	var missing []string
+	for _, field := range o.opts.RequiredTags {
+		if slices.Contains(info.Missing, strings.ToLower(field)) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingTags, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ProcessFile copies (or moves) a single music file into the target folder. Copy and delete
+// failures are returned; existing files are skipped and not treated as errors. The result says
+// what happened, and carries the destination path whenever it is known. In a dry run that is
+// the path the file would get.
+func (o *Organizer) ProcessFile(ctx context.Context, file string, targetFolder string) (Result, error) {
+	return o.processFile(ctx, file, filepath.Dir(file), targetFolder)
+}
+
+// processFile does the work of ProcessFile, with the source folder the file was found under
+func (o *Organizer) processFile(ctx context.Context, file string, sourceFolder string, targetFolder string) (Result, error) {
+	result := Result{Source: file}
+	if ctx.Err() != nil {
+		result.Status = StatusFailed
+		return result, ctx.Err()
+	}
+
+	// Take the size now, before a move removes the source
+	if stat, err := os.Stat(file); err == nil {
+		result.Bytes = stat.Size()
+	}
+
+	targetFolder = o.targetFor(file, targetFolder)
+
+	if len(o.opts.RequiredTags) > 0 {
+		err := o.checkRequiredTags(file)
+		if err != nil {
+			o.log.Error("File is missing tags", "file", file, "error", err)
+			result.Status = StatusFailed
+			return result, err
+		}
+	}
+
+	if o.opts.DryRun {
+		// Work out where the file would go without copying anything
+		resultFileName, err := o.Destination(file, sourceFolder, targetFolder)
+		if err != nil {
+			o.log.Error("Error planning file", "file", file, "error", err)
+			result.Status = StatusFailed
+			return result, err
+		}
+
+		if o.opts.Move {
+			o.log.Info("Would move file", "file", file, "destination", resultFileName)
+		} else {
+			o.log.Info("Would copy file", "file", file, "destination", resultFileName)
+		}
+
+		destBase := strings.TrimSuffix(resultFileName, filepath.Ext(resultFileName))
+		for _, sidecar := range findSidecars(file, o.opts.Sidecars) {
+			o.log.Info("Would carry sidecar", "file", sidecar, "destination", destBase+filepath.Ext(sidecar))
+		}
+		if o.converts(file) {
+			o.log.Info("Would convert file", "file", file, "format", o.opts.Transcoder.Ext())
+		}
+		if o.opts.PreHook != "" || o.opts.PostHook != "" {
+			o.log.Info("Would run hooks", "file", file)
+		}
+		result.Destination = resultFileName
+		result.Status = StatusDryRun
+		return result, nil
+	}
+
+	if o.opts.PreHook != "" {
+		err := o.runHook(ctx, "pre", o.opts.PreHook, file)
+		if err != nil {
+			o.log.Error("Error running pre hook", "file", file, "error", err)
+			result.Status = StatusFailed
+			return result, err
+		}
+	}
+
+	if o.opts.Move {
+		o.log.Debug("Moving file", "file", file)
+	} else {
+		o.log.Debug("Copying file", "file", file)
+	}
+
+	remembered, found := "", false
+	if o.decisions != nil && !o.opts.Recompute {
+		remembered, found = o.decisions.lookup(file, targetFolder)
+	}
+
+	// A converted file is written straight to its destination, so that is worked out up front
+	converting := o.converts(file)
+	planned := remembered
+	var planErr error
+	if !found && (converting || o.opts.DirMode != 0) {
+		planned, planErr = o.Destination(file, sourceFolder, targetFolder)
+	}
+
+	// DirMode only applies to the folders this copy creates, so note which are missing before
+	// anything is created. Folders already in the library keep their permissions.
+	var newFolders []string
+	if o.opts.DirMode != 0 && planned != "" {
+		newFolders = missingFolders(targetFolder, filepath.Dir(planned))
+	}
+
+	if o.opts.BucketByLetter && !o.opts.KeepStructure && !found {
+		info, _ := musicutils.ReadTrackInfo(file)
+		targetFolder = filepath.Join(targetFolder, musicutils.BucketLetter(info.Artist))
+		err := os.MkdirAll(targetFolder, 0755)
+		if err != nil {
+			o.log.Error("Error creating bucket folder", "folder", targetFolder, "error", err)
+			result.Status = StatusFailed
+			return result, err
+		}
+	}
+
+	var resultFileName string
+	var err error
+	if converting {
+		resultFileName, err = planned, planErr
+		if err == nil {
+			resultFileName, err = o.transcode(ctx, file, planned)
+		}
+	} else if found {
+		o.log.Debug("Using remembered destination", "file", file, "destination", remembered)
+		resultFileName, err = copyToPath(ctx, file, remembered)
+	} else if o.opts.KeepStructure {
+		resultFileName, err = copyToPath(ctx, file, structureDestination(file, sourceFolder, targetFolder))
+	} else {
+		resultFileName, err = o.copyMusic(file, targetFolder)
+	}
+	result.Destination = resultFileName
+
+	// Check if the file is the same as the result file. Comparing the files themselves catches
+	// paths that only differ in case on a case-insensitive filesystem.
+	sameFile := resultFileName == file || isSameFile(file, resultFileName)
+
+	if err == movemusic.ErrFileExists && o.opts.Update && !converting && !sameFile && isNewer(file, resultFileName) {
+		o.log.Info("Source file is newer, updating", "file", file, "destination", resultFileName)
+		err = o.backUp(resultFileName)
+		if err == nil {
+			err = musicutils.CopyFile(ctx, file, resultFileName)
+		}
+	}
+
+	if o.decisions != nil && resultFileName != "" && (err == nil || err == movemusic.ErrFileExists) {
+		o.decisions.record(file, resultFileName)
+	}
+
+	if err != nil {
+		if err == movemusic.ErrFileExists && sameFile {
+			o.log.Info("File is already organized, skipping", "file", file)
+			result.Status = StatusSkipped
+			result.Reason = SkipAlreadyOrganized
+		} else if err == movemusic.ErrFileExists {
+			o.log.Info("File already exists, skipping", "file", file, "destination", resultFileName)
+			result.Status = StatusSkipped
+			result.Reason = SkipAlreadyExists
+
+			if o.opts.Move {
+				o.copySidecars(ctx, file, resultFileName)
+
+				// Delete the source file
+				o.log.Debug("Deleting source file", "file", file)
+				err := o.removeSource(file)
+
+				if err != nil {
+					o.log.Error("Error deleting file", "file", file, "error", err)
+					result.Status = StatusFailed
+					return result, err
+				}
+			}
+		} else {
+			o.log.Error("Error copying file", "file", file, "error", err)
+			result.Status = StatusFailed
+			return result, err
+		}
+
+		return result, nil
+	}
+
+	result.Status = StatusCopied
+	o.applyFileMode(resultFileName)
+	o.applyDirMode(newFolders)
+
+	if !sameFile {
+		o.copySidecars(ctx, file, resultFileName)
+	}
+
+	if o.opts.Move && !sameFile {
+
+		// Delete the source file
+		o.log.Debug("Deleting source file", "file", file)
+		err := o.removeSource(file)
+
+		if err != nil {
+			o.log.Error("Error deleting file", "file", file, "error", err)
+			result.Status = StatusFailed
+			return result, err
+		}
+		result.Status = StatusMoved
+	}
+
+	o.log.Info("Finished", "file", file, "destination", resultFileName)
+
+	if o.opts.PostHook != "" {
+		err := o.runHook(ctx, "post", o.opts.PostHook, file, resultFileName)
+		if err != nil {
+			o.log.Error("Error running post hook", "file", file, "error", err)
+		}
+	}
+	return result, nil
+}
+
+// Destination returns the path the file gets under the target folder with the organizer's
+// options, without copying anything. The source folder is the one the file was found under,
+// which the path is relative to with KeepStructure. A file to be converted gets the
+// Transcoder's extension.
+func (o *Organizer) Destination(file string, sourceFolder string, targetFolder string) (string, error) {
+	var destination string
+	var err error
+	if o.opts.KeepStructure {
+		destination = structureDestination(file, sourceFolder, targetFolder)
+	} else {
+		if o.opts.BucketByLetter {
+			info, _ := musicutils.ReadTrackInfo(file)
+			targetFolder = filepath.Join(targetFolder, musicutils.BucketLetter(info.Artist))
+		}
+		destination, err = o.destination(file, targetFolder)
+	}
+	if err == nil && o.converts(file) {
+		destination = strings.TrimSuffix(destination, filepath.Ext(destination)) + o.opts.Transcoder.Ext()
+	}
+	return destination, err
+}
+
+// targetFor returns the target folder the file goes to: its format's own target if it has one,
+// otherwise the target folder given
+func (o *Organizer) targetFor(file string, targetFolder string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	if formatTarget, found := o.opts.FormatTargets[ext]; found {
+		return formatTarget
+	}
+	return targetFolder
+}
+
+// copyMusic is movemusic.CopyMusic, replaceable in tests
+var copyMusic = movemusic.CopyMusic
+
+// destination returns the path the file gets in the target folder: movemusic's name for it,
+// without the doubled extension movemusic gives some files (see fixDoubledExt)
+func (o *Organizer) destination(file string, targetFolder string) (string, error) {
+	name, err := musicutils.DestinationName(file, o.opts.UseFolders)
+	if err != nil {
+		return "", err
+	}
+	return fixDoubledExt(file, filepath.Join(targetFolder, name)), nil
+}
+
+// copyMusic places the file with movemusic and renames a copy it gave a doubled extension. The
+// corrected name is checked before copying, so a file placed by an earlier run is skipped with
+// movemusic.ErrFileExists instead of being copied again.
+func (o *Organizer) copyMusic(file string, targetFolder string) (string, error) {
+	if strings.ToLower(filepath.Ext(file)) == filepath.Ext(file) {
+		return copyMusic(file, targetFolder, o.opts.UseFolders)
+	}
+
+	fixed, err := o.destination(file, targetFolder)
+	if err != nil {
+		return "", err
+	}
+	if musicutils.FileExists(fixed) {
+		return fixed, movemusic.ErrFileExists
+	}
+
+	placed, err := copyMusic(file, targetFolder, o.opts.UseFolders)
+	if err != nil || placed == fixed {
+		return placed, err
+	}
+	return fixed, os.Rename(placed, fixed)
+}
+
+// fixDoubledExt returns the destination without the source's extension repeated. For a file
+// with no title tag movemusic uses the file name as the title, trimming only the lower case
+// extension, so an untagged Song.MP3 lands as "01 - Song.mp3.mp3".
+func fixDoubledExt(file string, destination string) string {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == filepath.Ext(file) {
+		return destination
+	}
+
+	stem := strings.TrimSuffix(destination, ext)
+	if !strings.EqualFold(filepath.Ext(stem), ext) {
+		return destination
+	}
+	return strings.TrimSuffix(stem, filepath.Ext(stem)) + ext
+}
+
+// structureDestination returns the same relative path under the target folder as the file has
+// under the source folder, with each part of the path sanitized
+func structureDestination(file string, sourceFolder string, targetFolder string) string {
+	relPath, inside := musicutils.RelPathInside(sourceFolder, file)
+	if !inside {
+		relPath = filepath.Base(file)
+	}
+
+	parts := strings.Split(relPath, string(filepath.Separator))
+	for i, part := range parts {
+		parts[i] = musicutils.SanitizeName(part)
+	}
+	return filepath.Join(append([]string{targetFolder}, parts...)...)
+}
+
+// copyToPath copies the file to the given destination. Like movemusic.CopyMusic it returns
+// movemusic.ErrFileExists, with the destination, if the file is already there.
+func copyToPath(ctx context.Context, file string, target string) (string, error) {
+	if musicutils.FileExists(target) {
+		return target, movemusic.ErrFileExists
+	}
+	return target, musicutils.CopyFile(ctx, file, target)
+}
+
+// isSameFile checks whether both paths lead to the same file on disk
+func isSameFile(file string, other string) bool {
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+	otherInfo, err := os.Stat(other)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fileInfo, otherInfo)
+}
+
+// isNewer checks whether the file was modified more recently than the other file
+func isNewer(file string, other string) bool {
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+	otherInfo, err := os.Stat(other)
+	if err != nil {
+		return false
+	}
+	return fileInfo.ModTime().After(otherInfo.ModTime())
+}
+
+// writable is isWritable, replaceable in tests
+var writable = isWritable
+
+// isWritable checks whether files can be created (and so removed) in the folder by creating
+// and removing a probe file
+func isWritable(folder string) bool {
+	probe, err := os.CreateTemp(folder, ".muxic-probe-*")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return true
+}
+
+// removeSource deletes a source file that has been moved, or sends it to the trash
+func (o *Organizer) removeSource(file string) error {
+	if o.opts.Trash {
+		return musicutils.TrashFile(file)
+	}
+	return os.Remove(file)
+}
@@ -0,0 +1,427 @@
+package organize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"muxic/internal/testutil"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/punkscience/movemusic"
+)
+
+func TestCancelledRunCopiesNothing(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "one.mp3"), nil)
+	testutil.WriteMP3(t, filepath.Join(source, "two.mp3"), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary, err := New(Options{UseFolders: true, Logger: testutil.Logger(io.Discard)}).Organize(ctx, source, target)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the run to be cancelled, got %v", err)
+	}
+	if summary.Completed != 0 || len(summary.Errors) != 0 {
+		t.Fatalf("got %d completed and %d errors, want nothing done", summary.Completed, len(summary.Errors))
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected nothing copied after cancelling, found %v", files)
+	}
+}
+
+func TestFailFastStopsAtFirstFailure(t *testing.T) {
+	source := t.TempDir()
+	for _, name := range []string{"1", "2", "3"} {
+		testutil.WriteMP3(t, filepath.Join(source, name+".mp3"), nil)
+	}
+
+	// movemusic won't copy into a folder that doesn't exist
+	missing := filepath.Join(t.TempDir(), "missing")
+	for _, failFast := range []bool{false, true} {
+		summary, err := New(Options{UseFolders: true, FailFast: failFast, Logger: testutil.Logger(io.Discard)}).Organize(context.Background(), source, missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := 3
+		if failFast {
+			want = 1
+		}
+		if summary.Total != 3 || summary.Completed != want || len(summary.Errors) != want {
+			t.Errorf("fail fast %v: expected %d of 3 files done and failed, got %d of %d and %d", failFast, want, summary.Completed, summary.Total, len(summary.Errors))
+		}
+		if first := filepath.Join(source, "1.mp3"); len(summary.Errors) > 0 && summary.Errors[0].Path != first {
+			t.Errorf("fail fast %v: expected the first failure to be %s, got %s", failFast, first, summary.Errors[0].Path)
+		}
+	}
+}
+
+func TestUpperCaseExtensionsComeOutLowerCase(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Tagged.MP3"), testutil.Track("Artist", "Album", "Tagged", "1"))
+	testutil.WriteFile(t, filepath.Join(source, "Untagged.MP3"), []byte{0xff, 0xfb, 0x90, 0x00})
+
+	// Count the copies movemusic is asked to make
+	var copies []string
+	t.Cleanup(func() { copyMusic = movemusic.CopyMusic })
+	copyMusic = func(file string, targetFolder string, useFolders bool) (string, error) {
+		placed, err := movemusic.CopyMusic(file, targetFolder, useFolders)
+		if err == nil {
+			copies = append(copies, filepath.Base(file))
+		}
+		return placed, err
+	}
+
+	organizer := New(Options{UseFolders: true, Logger: testutil.Logger(io.Discard)})
+	for run := 1; run <= 2; run++ {
+		copies = nil
+		summary, err := organizer.Organize(context.Background(), source, target)
+		if err != nil || len(summary.Errors) != 0 {
+			t.Fatalf("run %d: unexpected failure: %v %v", run, err, summary.Errors)
+		}
+
+		want := []string{"Artist/Album/01 - Tagged.mp3", "Unknown/Unknown/01 - Untagged.mp3"}
+		if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+			t.Errorf("run %d: expected %v, found %v", run, want, files)
+		}
+		if run == 2 && len(copies) != 0 {
+			t.Errorf("expected the second run to skip everything, copied %v", copies)
+		}
+	}
+
+	result, err := New(Options{UseFolders: true, DryRun: true, Logger: testutil.Logger(io.Discard)}).ProcessFile(context.Background(), filepath.Join(source, "Untagged.MP3"), target)
+	if want := filepath.Join(target, "Unknown", "Unknown", "01 - Untagged.mp3"); result.Destination != want || err != nil {
+		t.Errorf("expected the dry run to plan %s, got %s, %v", want, result.Destination, err)
+	}
+}
+
+func TestMoveToTrash(t *testing.T) {
+	source, target, home := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	testutil.WriteMP3(t, filepath.Join(source, "song.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+
+	organizer := New(Options{UseFolders: true, Move: true, Trash: true, Logger: testutil.Logger(io.Discard)})
+	if _, err := organizer.Organize(context.Background(), source, target); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := testutil.ListFiles(t, source); len(files) != 0 {
+		t.Errorf("expected the source moved out, found %v", files)
+	}
+	if trashed := testutil.ListFiles(t, home); !slices.Contains(trashed, "data/Trash/files/song.mp3") && !slices.Contains(trashed, ".Trash/song.mp3") && !slices.Contains(trashed, ".muxic/trash/song.mp3") {
+		t.Errorf("expected the source in a trash folder, found %v", trashed)
+	}
+}
+
+func TestSecondRunReportsAlreadyExists(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	file := filepath.Join(source, "song.mp3")
+	testutil.WriteMP3(t, file, testutil.Track("Artist", "Album", "Song", "1"))
+	organizer := New(Options{UseFolders: true, Logger: testutil.Logger(io.Discard)})
+
+	result, err := organizer.ProcessFile(context.Background(), file, target)
+	if err != nil || result.Status != StatusCopied {
+		t.Fatalf("expected the first run to copy, got %+v, %v", result, err)
+	}
+
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []musicutils.SkippedFile{{Path: file, Reason: SkipAlreadyExists}}
+	if !slices.Equal(summary.Skipped, want) {
+		t.Errorf("expected %v, got %v", want, summary.Skipped)
+	}
+}
+
+func TestBucketByLetterUsesCleanedArtist(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "1.mp3"), testutil.Track("'Til Tuesday", "Voices Carry", "Voices Carry", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "2.mp3"), testutil.Track("10cc", "Sheet Music", "Wall Street Shuffle", "1"))
+
+	organizer := New(Options{UseFolders: true, BucketByLetter: true, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	want := []string{"#/10Cc/Sheet Music/01 - Wall Street Shuffle.mp3", "T/Til Tuesday/Voices Carry/01 - Voices Carry.mp3"}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, want) {
+		t.Errorf("expected %v, found %v", want, files)
+	}
+}
+
+func TestKeepStructureMirrorsSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the source names can't be created on Windows")
+	}
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Live: 1999?", "Disc 1.", "track*.mp3"), testutil.Track("Artist", "Album", "Song", "1"))
+	want := filepath.Join(target, "Live- 1999-", "Disc 1", "track-.mp3")
+
+	var out bytes.Buffer
+	opts := Options{UseFolders: true, KeepStructure: true, DryRun: true, Logger: testutil.Logger(&out)}
+	_, err := New(opts).Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), fmt.Sprintf("destination=%q", want)) {
+		t.Errorf("expected the dry run to plan %s:\n%s", want, out.String())
+	}
+
+	opts.DryRun = false
+	summary, err := New(opts).Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+	if files := testutil.ListFiles(t, target); !slices.Equal(files, []string{"Live- 1999-/Disc 1/track-.mp3"}) {
+		t.Errorf("expected the source layout, found %v", files)
+	}
+}
+
+func TestReadOnlySourceFallsBackToCopy(t *testing.T) {
+	defer func(saved func(string) bool) { writable = saved }(writable)
+	writable = func(string) bool { return false }
+
+	source, target := t.TempDir(), t.TempDir()
+	song := filepath.Join(source, "song.mp3")
+	testutil.WriteMP3(t, song, testutil.Track("Artist", "Album", "Song", "1"))
+
+	_, err := New(Options{Move: true, UseFolders: true, Logger: testutil.Logger(io.Discard)}).Organize(context.Background(), source, target)
+	if !errors.Is(err, ErrReadOnlySource) {
+		t.Errorf("expected ErrReadOnlySource without the fallback, got %v", err)
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected nothing copied, found %v", files)
+	}
+
+	var logs bytes.Buffer
+	organizer := New(Options{Move: true, AllowCopyFallback: true, UseFolders: true, Logger: testutil.Logger(&logs)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+	if !strings.Contains(logs.String(), "read-only") {
+		t.Errorf("expected a warning about the read-only source, logged:\n%s", logs.String())
+	}
+	if len(summary.Results) != 1 || summary.Results[0].Status != StatusCopied {
+		t.Errorf("expected the file to be copied, got %v", summary.Results)
+	}
+	if !musicutils.FileExists(song) || !musicutils.FileExists(filepath.Join(target, "Artist", "Album", "01 - Song.mp3")) {
+		t.Error("expected the file both in the source and the target")
+	}
+}
+
+func TestIsWritable(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("needs a platform and user that folder permissions apply to")
+	}
+	folder := t.TempDir()
+	if !isWritable(folder) {
+		t.Error("expected a new temporary folder to be writable")
+	}
+	if entries, _ := os.ReadDir(folder); len(entries) != 0 {
+		t.Errorf("expected no probe file left behind, found %v", entries)
+	}
+
+	err := os.Chmod(folder, 0555)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(folder, 0755)
+	if isWritable(folder) {
+		t.Error("expected a read-only folder not to be writable")
+	}
+}
+
+func TestUpdateReplacesOnlyOlderFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		exists  bool
+		replace bool
+	}{
+		{"destination newer", time.Hour, true, false},
+		{"source newer", -time.Hour, true, true},
+		{"destination absent", 0, false, true},
+	}
+	for _, test := range tests {
+		source, target := t.TempDir(), t.TempDir()
+		song := filepath.Join(source, "song.mp3")
+		testutil.WriteMP3(t, song, testutil.Track("Artist", "Album", "Song", "1"))
+		destination := filepath.Join(target, "Artist", "Album", "01 - Song.mp3")
+		if test.exists {
+			testutil.WriteFile(t, destination, []byte("old"))
+			modified := time.Now().Add(test.age)
+			os.Chtimes(destination, modified, modified)
+		}
+
+		organizer := New(Options{UseFolders: true, Update: true, Logger: testutil.Logger(io.Discard)})
+		summary, err := organizer.Organize(context.Background(), source, target)
+		if err != nil || len(summary.Errors) != 0 {
+			t.Fatalf("%s: unexpected failure: %v %v", test.name, err, summary.Errors)
+		}
+
+		want, _ := os.ReadFile(song)
+		if !test.replace {
+			want = []byte("old")
+		}
+		got, _ := os.ReadFile(destination)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: expected the destination to be replaced: %v", test.name, test.replace)
+		}
+	}
+}
+
+func TestStructureDestinationKeepsDotDotNames(t *testing.T) {
+	source, target := filepath.FromSlash("/music/in"), filepath.FromSlash("/music/out")
+	tests := map[string]string{
+		"/music/in/..odd/song.mp3": "/music/out/..odd/song.mp3",
+		"/music/in2/song.mp3":      "/music/out/song.mp3",
+	}
+	for file, want := range tests {
+		if got := structureDestination(filepath.FromSlash(file), source, target); got != filepath.FromSlash(want) {
+			t.Errorf("%s: expected %s, got %s", file, want, got)
+		}
+	}
+}
+
+func TestAlreadyOrganizedFileIsLeftAlone(t *testing.T) {
+	library := t.TempDir()
+	song := filepath.Join(library, "Artist", "Album", "01 - Song.mp3")
+	testutil.WriteMP3(t, song, testutil.Track("Artist", "Album", "Song", "1"))
+
+	organizer := New(Options{Move: true, UseFolders: true, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), library, library)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	if len(summary.Results) != 1 || summary.Results[0].Reason != SkipAlreadyOrganized {
+		t.Errorf("expected the file to be skipped as already organized, got %v", summary.Results)
+	}
+	if files := testutil.ListFiles(t, library); !slices.Equal(files, []string{"Artist/Album/01 - Song.mp3"}) {
+		t.Errorf("expected the file left where it was, found %v", files)
+	}
+}
+
+func TestMaxFilesCap(t *testing.T) {
+	source := t.TempDir()
+	for _, name := range []string{"1", "2", "3"} {
+		testutil.WriteMP3(t, filepath.Join(source, name+".mp3"), testutil.Track("Artist", "Album", "Song "+name, name))
+	}
+
+	for _, confirm := range []bool{false, true} {
+		target := t.TempDir()
+		asked := 0
+		organizer := New(Options{UseFolders: true, MaxFiles: 2, Logger: testutil.Logger(io.Discard), ConfirmTooMany: func(total int, max int) bool {
+			asked++
+			return confirm
+		}})
+		summary, err := organizer.Organize(context.Background(), source, target)
+
+		if asked != 1 {
+			t.Errorf("confirm %v: expected to be asked once, asked %d times", confirm, asked)
+		}
+		if !confirm && (!errors.Is(err, ErrTooManyFiles) || summary.Completed != 0 || len(testutil.ListFiles(t, target)) != 0) {
+			t.Errorf("expected the run to stop with nothing processed, got %v with %d completed", err, summary.Completed)
+		}
+		if confirm && (err != nil || summary.Completed != 3) {
+			t.Errorf("expected a confirmed run to process every file, got %v with %d completed", err, summary.Completed)
+		}
+	}
+}
+
+func TestRequiredTags(t *testing.T) {
+	source := t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "untagged.mp3"), map[string]string{"TIT2": "Title Only"})
+	testutil.WriteMP3(t, filepath.Join(source, "no-album.mp3"), map[string]string{"TPE1": "Artist", "TIT2": "Song"})
+
+	tests := []struct {
+		required []string
+		errors   int
+	}{
+		{nil, 0},
+		{[]string{"artist", "album", "title"}, 2},
+		{[]string{"artist", "title"}, 1},
+	}
+	for _, test := range tests {
+		organizer := New(Options{UseFolders: true, RequiredTags: test.required, Logger: testutil.Logger(io.Discard)})
+		summary, err := organizer.Organize(context.Background(), source, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summary.Errors) != test.errors {
+			t.Errorf("requiring %v: expected %d errors, got %v", test.required, test.errors, summary.Errors)
+		}
+		for _, fe := range summary.Errors {
+			if !errors.Is(fe.Err, ErrMissingTags) {
+				t.Errorf("requiring %v: expected ErrMissingTags, got %v", test.required, fe.Err)
+			}
+		}
+	}
+}
+
+func TestFormatTargetsSplitMixedSource(t *testing.T) {
+	source, target, flacs := t.TempDir(), t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "lossy.mp3"), testutil.Track("Artist", "Album", "Lossy", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "lossless.FLAC"), testutil.Track("Artist", "Album", "Lossless", "2"))
+
+	organizer := New(Options{UseFolders: true, FormatTargets: map[string]string{"flac": flacs}, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil || len(summary.Errors) != 0 {
+		t.Fatalf("unexpected failure: %v %v", err, summary.Errors)
+	}
+
+	if files, want := testutil.ListFiles(t, target), []string{"Artist/Album/01 - Lossy.mp3"}; !slices.Equal(files, want) {
+		t.Errorf("expected %v in the target, found %v", want, files)
+	}
+	if files, want := testutil.ListFiles(t, flacs), []string{"Artist/Album/02 - Lossless.flac"}; !slices.Equal(files, want) {
+		t.Errorf("expected %v in the flac target, found %v", want, files)
+	}
+}
+
+func TestBytesRemovedMatchesMovedSources(t *testing.T) {
+	for _, move := range []bool{false, true} {
+		source, target := t.TempDir(), t.TempDir()
+		testutil.WriteMP3(t, filepath.Join(source, "1.mp3"), testutil.Track("Artist", "Album", "Short", "1"))
+		testutil.WriteMP3(t, filepath.Join(source, "2.mp3"), testutil.Track("Artist", "Album", "A Much Longer Title", "2"))
+		testutil.WriteMP3(t, filepath.Join(source, "3.mp3"), testutil.Track("Artist", "Album", "Already There", "3"))
+		testutil.WriteMP3(t, filepath.Join(target, "Artist", "Album", "03 - Already There.mp3"), testutil.Track("Artist", "Album", "Already There", "3"))
+		testutil.WriteMP3(t, filepath.Join(source, "broken.mp3"), map[string]string{"TIT2": "No Artist"})
+
+		// The moved files and the one already in the target go; the failed one stays
+		var want int64
+		for _, name := range []string{"1.mp3", "2.mp3", "3.mp3"} {
+			stat, err := os.Stat(filepath.Join(source, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want += stat.Size()
+		}
+		if !move {
+			want = 0
+		}
+
+		organizer := New(Options{Move: move, UseFolders: true, RequiredTags: []string{"artist"}, Logger: testutil.Logger(io.Discard)})
+		summary, err := organizer.Organize(context.Background(), source, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.BytesRemoved != want {
+			t.Errorf("move %v: expected %d bytes removed, got %d", move, want, summary.BytesRemoved)
+		}
+	}
+}
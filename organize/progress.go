@@ -0,0 +1,69 @@
+package organize
+
+import "time"
+
+// Progress describes how far through its bytes an Organize run is
+type Progress struct {
+	// Files is the number of files processed so far, out of TotalFiles
+	Files      int
+	TotalFiles int
+
+	// Bytes is the size of the files processed so far, out of TotalBytes
+	Bytes      int64
+	TotalBytes int64
+
+	// Percent is Bytes as a percentage of TotalBytes
+	Percent float64
+
+	// ETA is the estimated time left, or 0 until there's a throughput to go on
+	ETA time.Duration
+}
+
+// progressSmoothing is the weight each new throughput sample gets in the moving average, so a
+// run of tiny or huge files doesn't swing the ETA about
+const progressSmoothing = 0.2
+
+// progressTracker turns per-file byte counts into a Progress, estimating the time left from a
+// moving average of the throughput
+type progressTracker struct {
+	now func() time.Time
+
+	totalFiles int
+	totalBytes int64
+	files      int
+	bytes      int64
+
+	last time.Time
+	rate float64 // bytes per second, smoothed
+}
+
+// newProgressTracker starts tracking a run of files adding up to totalBytes
+func newProgressTracker(totalFiles int, totalBytes int64, now func() time.Time) *progressTracker {
+	return &progressTracker{now: now, totalFiles: totalFiles, totalBytes: totalBytes, last: now()}
+}
+
+// add records one more file of the given size as done and returns the progress so far
+func (t *progressTracker) add(size int64) Progress {
+	t.files++
+	t.bytes += size
+
+	now := t.now()
+	if elapsed := now.Sub(t.last).Seconds(); elapsed > 0 {
+		sample := float64(size) / elapsed
+		if t.rate == 0 {
+			t.rate = sample
+		} else {
+			t.rate = progressSmoothing*sample + (1-progressSmoothing)*t.rate
+		}
+	}
+	t.last = now
+
+	progress := Progress{Files: t.files, TotalFiles: t.totalFiles, Bytes: t.bytes, TotalBytes: t.totalBytes, Percent: 100}
+	if t.totalBytes > 0 {
+		progress.Percent = float64(t.bytes) * 100 / float64(t.totalBytes)
+	}
+	if remaining := t.totalBytes - t.bytes; remaining > 0 && t.rate > 0 {
+		progress.ETA = time.Duration(float64(remaining) / t.rate * float64(time.Second))
+	}
+	return progress
+}
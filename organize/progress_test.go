@@ -0,0 +1,44 @@
+package organize
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	clock := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tracker := newProgressTracker(3, 1000, func() time.Time { return clock })
+
+	steps := []struct {
+		after   time.Duration
+		size    int64
+		percent float64
+		eta     time.Duration
+	}{
+		// 100 B/s to start with
+		{time.Second, 100, 10, 9 * time.Second},
+
+		// A 400 B/s sample only moves the average a fifth of the way, to 160 B/s
+		{time.Second, 400, 50, 3125 * time.Millisecond},
+
+		// Nothing left, so no ETA
+		{2 * time.Second, 500, 100, 0},
+	}
+	for i, step := range steps {
+		clock = clock.Add(step.after)
+		progress := tracker.add(step.size)
+		if progress.Files != i+1 || progress.TotalFiles != 3 || progress.TotalBytes != 1000 {
+			t.Errorf("step %d: unexpected counts %+v", i+1, progress)
+		}
+		if progress.Percent != step.percent || progress.ETA != step.eta {
+			t.Errorf("step %d: expected %v%% with %v left, got %v%% with %v", i+1, step.percent, step.eta, progress.Percent, progress.ETA)
+		}
+	}
+}
+
+func TestProgressTrackerNoBytes(t *testing.T) {
+	tracker := newProgressTracker(1, 0, time.Now)
+	if progress := tracker.add(0); progress.Percent != 100 || progress.ETA != 0 {
+		t.Errorf("expected an empty run to be complete, got %+v", progress)
+	}
+}
@@ -0,0 +1,47 @@
+package organize
+
+import (
+	"context"
+	"fmt"
+	"muxic/musicutils"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// quarantineLogName is the file inside the quarantine folder recording why each file is there
+const quarantineLogName = "quarantine.log"
+
+// quarantineFile copies (or moves) a file that failed processing into the quarantine folder,
+// keeping its path relative to the source folder, and records the reason in the quarantine log
+func (o *Organizer) quarantineFile(ctx context.Context, file string, sourceFolder string, reason error) error {
+	quarantineFolder := o.opts.QuarantineFolder
+
+	relPath, inside := musicutils.RelPathInside(sourceFolder, file)
+	if !inside {
+		relPath = filepath.Base(file)
+	}
+	target := filepath.Join(quarantineFolder, relPath)
+
+	o.log.Warn("Quarantining file", "file", file, "destination", target)
+	err := musicutils.CopyFile(ctx, file, target)
+	if err != nil {
+		return err
+	}
+
+	if o.opts.Move {
+		err = o.removeSource(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(quarantineFolder, quarantineLogName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	_, err = fmt.Fprintf(logFile, "%s\t%s\t%v\n", time.Now().Format(time.RFC3339), file, reason)
+	return err
+}
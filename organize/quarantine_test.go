@@ -0,0 +1,70 @@
+package organize
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBrokenFileIsQuarantined(t *testing.T) {
+	source, target, quarantine := t.TempDir(), t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "Good", "01.mp3"), testutil.Track("Artist", "Album", "Good", "1"))
+
+	// movemusic can't place .m4a files, so this one fails
+	testutil.WriteFile(t, filepath.Join(source, "Broken", "01.m4a"), []byte("not really AAC"))
+
+	organizer := New(Options{UseFolders: true, Move: true, QuarantineFolder: quarantine, Logger: testutil.Logger(io.Discard)})
+	summary, err := organizer.Organize(context.Background(), source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Errors) != 1 {
+		t.Errorf("expected the broken file to fail, got %v", summary.Errors)
+	}
+
+	if files, want := testutil.ListFiles(t, quarantine), []string{"Broken/01.m4a", quarantineLogName}; !slices.Equal(files, want) {
+		t.Errorf("expected %v in quarantine, found %v", want, files)
+	}
+	log, err := os.ReadFile(filepath.Join(quarantine, quarantineLogName))
+	if err != nil || !strings.Contains(string(log), "unsupported file type") {
+		t.Errorf("expected the reason in the log, got %q, %v", log, err)
+	}
+	if files, want := testutil.ListFiles(t, target), []string{"Artist/Album/01 - Good.mp3"}; !slices.Equal(files, want) {
+		t.Errorf("expected %v organized, found %v", want, files)
+	}
+	if files := testutil.ListFiles(t, source); len(files) != 0 {
+		t.Errorf("expected everything moved out of the source, found %v", files)
+	}
+}
+
+func TestQuarantineInsideSourceIsNotScanned(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	quarantine := filepath.Join(source, "quarantine")
+	testutil.WriteFile(t, filepath.Join(quarantine, "earlier.m4a"), []byte("quarantined last time"))
+
+	summary, err := New(Options{UseFolders: true, QuarantineFolder: quarantine, Logger: testutil.Logger(io.Discard)}).Organize(context.Background(), source, target)
+	if err != nil || summary.Total != 0 {
+		t.Errorf("expected the quarantined file to be left alone, got %d files, %v", summary.Total, err)
+	}
+}
+
+func TestDryRunDoesNotQuarantine(t *testing.T) {
+	source, target, quarantine := t.TempDir(), t.TempDir(), t.TempDir()
+	testutil.WriteFile(t, filepath.Join(source, "01.m4a"), []byte("not really AAC"))
+
+	var out bytes.Buffer
+	New(Options{UseFolders: true, DryRun: true, QuarantineFolder: quarantine, Logger: testutil.Logger(&out)}).Organize(context.Background(), source, target)
+
+	if !strings.Contains(out.String(), `msg="Would quarantine file"`) {
+		t.Errorf("expected the dry run to say it would quarantine the file, got:\n%s", out.String())
+	}
+	if files := testutil.ListFiles(t, quarantine); len(files) != 0 {
+		t.Errorf("expected nothing quarantined in a dry run, found %v", files)
+	}
+}
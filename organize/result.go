@@ -0,0 +1,41 @@
+package organize
+
+// Status says what happened to a single file
+type Status string
+
+const (
+	StatusCopied  Status = "copied"
+	StatusMoved   Status = "moved"
+	StatusSkipped Status = "skipped"
+	StatusDryRun  Status = "dry-run"
+	StatusFailed  Status = "failed"
+)
+
+// Reasons a file can be skipped while processing
+const (
+	// SkipAlreadyExists is for a file whose destination is already there
+	SkipAlreadyExists = "already-exists"
+
+	// SkipAlreadyOrganized is for a file that is already at its own destination
+	SkipAlreadyOrganized = "already-organized"
+
+	// SkipTooFewTracks is for a file whose album has fewer than MinTracks tracks
+	SkipTooFewTracks = "too-few-tracks"
+)
+
+// Result describes what happened to a single file
+type Result struct {
+	Source string
+
+	// Destination is empty when it isn't known, e.g. when the copy failed. In a dry run it is
+	// where the file would go.
+	Destination string
+
+	Status Status
+
+	// Bytes is the size of the source file
+	Bytes int64
+
+	// Reason says why a skipped file was skipped
+	Reason string
+}
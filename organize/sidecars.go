@@ -0,0 +1,52 @@
+package organize
+
+import (
+	"context"
+	"muxic/musicutils"
+	"path/filepath"
+	"strings"
+)
+
+// findSidecars returns the files next to the track that share its base name and have one of
+// the sidecar extensions, e.g. the .cue and .log for a ripped .flac
+func findSidecars(file string, extensions []string) []string {
+	var found []string
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+
+	for _, ext := range extensions {
+		sidecar := base + "." + strings.TrimPrefix(ext, ".")
+		if musicutils.FileExists(sidecar) {
+			found = append(found, sidecar)
+		}
+	}
+	return found
+}
+
+// copySidecars copies (or moves) the track's sidecar files next to its destination, renamed
+// to match the destination track name
+func (o *Organizer) copySidecars(ctx context.Context, file string, resultFileName string) {
+	destBase := strings.TrimSuffix(resultFileName, filepath.Ext(resultFileName))
+
+	for _, sidecar := range findSidecars(file, o.opts.Sidecars) {
+		target := destBase + filepath.Ext(sidecar)
+
+		if musicutils.FileExists(target) {
+			o.log.Debug("Sidecar already exists, skipping", "file", target)
+		} else {
+			o.log.Debug("Copying sidecar", "file", sidecar, "destination", target)
+			err := musicutils.CopyFile(ctx, sidecar, target)
+			if err != nil {
+				o.log.Error("Error copying sidecar", "file", sidecar, "error", err)
+				continue
+			}
+			o.applyFileMode(target)
+		}
+
+		if o.opts.Move {
+			err := o.removeSource(sidecar)
+			if err != nil {
+				o.log.Error("Error deleting sidecar", "file", sidecar, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package organize
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSidecarsTravelWithTrack(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	file := filepath.Join(source, "rip.flac")
+	testutil.WriteMP3(t, file, testutil.Track("Artist", "Album", "Song", "3"))
+	testutil.WriteFile(t, filepath.Join(source, "rip.cue"), []byte("FILE \"rip.flac\" WAVE"))
+	testutil.WriteFile(t, filepath.Join(source, "rip.log"), []byte("EAC log"))
+	testutil.WriteFile(t, filepath.Join(source, "other.log"), []byte("not a sidecar"))
+
+	opts := Options{UseFolders: true, Move: true, Sidecars: []string{"cue", ".log", "lrc"}, Logger: testutil.Logger(io.Discard)}
+	_, err := New(opts).ProcessFile(context.Background(), file, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"03 - Song.flac", "03 - Song.cue", "03 - Song.log"} {
+		if _, err := os.Stat(filepath.Join(target, "Artist", "Album", name)); err != nil {
+			t.Errorf("expected %s in the target: %v", name, err)
+		}
+	}
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "other.log" {
+		t.Errorf("expected only the unrelated log left behind, found %v", entries)
+	}
+}
+
+func TestSidecarsDryRun(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	file := filepath.Join(source, "rip.flac")
+	testutil.WriteMP3(t, file, testutil.Track("Artist", "Album", "Song", "3"))
+	testutil.WriteFile(t, filepath.Join(source, "rip.cue"), []byte("FILE \"rip.flac\" WAVE"))
+
+	var out bytes.Buffer
+	opts := Options{UseFolders: true, Move: true, DryRun: true, Sidecars: []string{"cue"}, Logger: testutil.Logger(&out)}
+	result, err := New(opts).ProcessFile(context.Background(), file, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join(target, "Artist", "Album", "03 - Song.flac"); result.Destination != want {
+		t.Errorf("expected the planned destination %s, got %s", want, result.Destination)
+	}
+	if want := filepath.Join(target, "Artist", "Album", "03 - Song.cue"); !strings.Contains(out.String(), want) {
+		t.Errorf("expected the sidecar's planned name %s in the output:\n%s", want, out.String())
+	}
+
+	if entries, _ := os.ReadDir(target); len(entries) != 0 {
+		t.Errorf("expected nothing in the target, found %v", entries)
+	}
+	if entries, _ := os.ReadDir(source); len(entries) != 2 {
+		t.Errorf("expected the source untouched, found %v", entries)
+	}
+}
@@ -0,0 +1,72 @@
+package organize
+
+import (
+	"errors"
+	"fmt"
+	"muxic/musicutils"
+	"os"
+)
+
+// ErrInsufficientSpace is returned by Organize when the target volume doesn't have room for the
+// files, unless IgnoreSpace is set
+var ErrInsufficientSpace = errors.New("not enough free space in the target folder")
+
+// freeSpace reads the free space of a folder's volume; tests replace it to simulate a full disk
+var freeSpace = musicutils.FreeSpace
+
+// checkSpace makes sure each target volume has room for the files going to it, whether to the
+// target folder or to a format target. When moving within one volume each source is removed
+// right after its copy, so only the largest file needs room. If a volume's free space can't be
+// read its check is skipped.
+func (o *Organizer) checkSpace(files []string, sourceFolder string, targetFolder string) error {
+	// Targets on the same volume share its free space, so their files are counted together
+	type volume struct {
+		target         string
+		total, largest uint64
+	}
+	var volumes []*volume
+
+	for _, file := range files {
+		stat, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		target := o.targetFor(file, targetFolder)
+		var v *volume
+		for _, candidate := range volumes {
+			if candidate.target == target || musicutils.SameVolume(candidate.target, target) {
+				v = candidate
+				break
+			}
+		}
+		if v == nil {
+			v = &volume{target: target}
+			volumes = append(volumes, v)
+		}
+
+		size := uint64(stat.Size())
+		v.total += size
+		if size > v.largest {
+			v.largest = size
+		}
+	}
+
+	for _, v := range volumes {
+		needed := v.total
+		if o.opts.Move && musicutils.SameVolume(sourceFolder, v.target) {
+			needed = v.largest
+		}
+
+		free, err := freeSpace(v.target)
+		if err != nil {
+			o.log.Warn("Couldn't check free space in the target folder", "folder", v.target, "error", err)
+			continue
+		}
+
+		if needed > free {
+			return fmt.Errorf("%w: %d bytes needed in %s, %d available", ErrInsufficientSpace, needed, v.target, free)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package organize
+
+import (
+	"context"
+	"errors"
+	"io"
+	"muxic/internal/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withFreeSpace makes every volume report the given free space for the rest of the test
+func withFreeSpace(t *testing.T, free uint64) {
+	t.Helper()
+	saved := freeSpace
+	freeSpace = func(string) (uint64, error) { return free, nil }
+	t.Cleanup(func() { freeSpace = saved })
+}
+
+func TestInsufficientSpaceStopsTheRun(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "02.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	stat, err := os.Stat(filepath.Join(source, "01.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for one file: enough for a move within the volume, not for a copy
+	withFreeSpace(t, uint64(stat.Size()))
+
+	_, err = New(Options{UseFolders: true, Logger: testutil.Logger(io.Discard)}).Organize(context.Background(), source, target)
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("expected ErrInsufficientSpace, got %v", err)
+	}
+	if files := testutil.ListFiles(t, target); len(files) != 0 {
+		t.Errorf("expected nothing copied, found %v", files)
+	}
+
+	summary, err := New(Options{UseFolders: true, IgnoreSpace: true, Logger: testutil.Logger(io.Discard)}).Organize(context.Background(), source, target)
+	if err != nil || summary.Completed != 2 {
+		t.Errorf("expected IgnoreSpace to copy anyway, got %v with %d completed", err, summary.Completed)
+	}
+}
+
+func TestMoveWithinVolumeNeedsRoomForLargestFile(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "01.mp3"), testutil.Track("Artist", "Album", "One", "1"))
+	testutil.WriteMP3(t, filepath.Join(source, "02.mp3"), testutil.Track("Artist", "Album", "Two", "2"))
+	stat, err := os.Stat(filepath.Join(source, "01.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFreeSpace(t, uint64(stat.Size()))
+
+	summary, err := New(Options{Move: true, UseFolders: true, Logger: testutil.Logger(io.Discard)}).Organize(context.Background(), source, target)
+	if err != nil || summary.Completed != 2 {
+		t.Errorf("expected the move to go ahead, got %v with %d completed", err, summary.Completed)
+	}
+}
+
+func TestFormatTargetSpaceIsChecked(t *testing.T) {
+	source, target, flacs := t.TempDir(), t.TempDir(), t.TempDir()
+	testutil.WriteMP3(t, filepath.Join(source, "lossless.flac"), testutil.Track("Artist", "Album", "Lossless", "1"))
+
+	// Only the flac target is full
+	saved := freeSpace
+	freeSpace = func(folder string) (uint64, error) {
+		if folder == flacs {
+			return 0, nil
+		}
+		return 1 << 30, nil
+	}
+	t.Cleanup(func() { freeSpace = saved })
+
+	organizer := New(Options{UseFolders: true, FormatTargets: map[string]string{"flac": flacs}, Logger: testutil.Logger(io.Discard)})
+	_, err := organizer.Organize(context.Background(), source, target)
+	if !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("expected the full format target to stop the run, got %v", err)
+	}
+}